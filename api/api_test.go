@@ -18,14 +18,33 @@ type ApiTestSuite struct {
 }
 
 func (t *ApiTestSuite) makeRequest(method, url string, body interface{}) *http.Response {
+	return t.makeAuthRequest(method, url, body, nil)
+}
+
+// makeAuthRequest - makes request with session cookies returned by signIn
+func (t *ApiTestSuite) makeAuthRequest(method, url string, body interface{}, cookies []*http.Cookie) *http.Response {
 	requestBody, _ := json.Marshal(body)
 	rq, _ := http.NewRequest(method, url, bytes.NewBuffer(requestBody))
 	rq.Header.Add("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		rq.AddCookie(cookie)
+	}
 	resp, err := t.server.Web.Test(rq, -1)
 	assert.Nil(t.T(), err, nil)
 	return resp
 }
 
+// signIn - signs up and signs in the user, returns session cookies
+func (t *ApiTestSuite) signIn(username string) []*http.Cookie {
+	user := SignUpReq{Username: username, Password: "secret"}
+	t.makeRequest("POST", "/api/v1/auth/signup", user)
+	resp := t.makeRequest("POST", "/api/v1/auth/signin", user)
+	if resp.StatusCode != http.StatusOK {
+		t.T().Fatalf("signin failed: %d", resp.StatusCode)
+	}
+	return resp.Cookies()
+}
+
 func parseResponse(t *testing.T, resp *http.Response) (response map[string]map[string]string) {
 
 	body, err := io.ReadAll(resp.Body)
@@ -40,6 +59,21 @@ func parseResponse(t *testing.T, resp *http.Response) (response map[string]map[s
 
 }
 
+// parseBody - decodes the whole response
+func parseBody[T any](t *testing.T, resp *http.Response) (body T) {
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Error(err)
+	}
+	return
+}
+
+// parseData - decodes data field of the response
+func parseData[T any](t *testing.T, resp *http.Response) T {
+	return parseBody[struct {
+		Data T `json:"data"`
+	}](t, resp).Data
+}
+
 func (ts *ApiTestSuite) TestSignUp() {
 	newUser := SignUpReq{
 		Username: "tim",
@@ -140,9 +174,11 @@ func (t *ApiTestSuite) SetupTest() {
 	if err != nil {
 		t.T().Errorf("test setup failed: %v", err)
 	}
+	t.server.InitExchange()
 }
 
 func (t *ApiTestSuite) TearDownTest() {
+	t.server.Exchange.Close()
 	err := t.server.CleanupModels()
 	if err != nil {
 		t.T().Errorf("test cleanup failed: %v", err)
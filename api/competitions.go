@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/timadinorth/bet-exchange/model"
+	"github.com/timadinorth/bet-exchange/util"
+	"gorm.io/gorm"
+)
+
+type CompetitionReq struct {
+	Name string `json:"name" validate:"required" example:"Premier League"`
+	Type string `json:"type" example:"league"`
+}
+
+// findCategory - loads category from category_id parameter, responds with
+// error status when it can not be found
+func (s *Server) findCategory(c *fiber.Ctx) (*model.Category, error) {
+	id, err := c.ParamsInt("category_id")
+	if err != nil || id <= 0 {
+		return nil, util.NewErrorStr(c, fiber.StatusBadRequest, "invalid category id")
+	}
+
+	var category model.Category
+	if err := s.DB.Take(&category, id).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewErrorStr(c, fiber.StatusNotFound, "category not found")
+	} else if err != nil {
+		return nil, util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return &category, nil
+}
+
+// findCompetition - loads competition from competition_id parameter within
+// the category, responds with error status when it can not be found
+func (s *Server) findCompetition(c *fiber.Ctx, category *model.Category) (*model.Competition, error) {
+	id, err := c.ParamsInt("competition_id")
+	if err != nil || id <= 0 {
+		return nil, util.NewErrorStr(c, fiber.StatusBadRequest, "invalid competition id")
+	}
+
+	var competition model.Competition
+	err = s.DB.Where("category_id = ?", category.ID).Take(&competition, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewErrorStr(c, fiber.StatusNotFound, "competition not found")
+	} else if err != nil {
+		return nil, util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return &competition, nil
+}
+
+// parseCompetition - parses and validates request body
+func (s *Server) parseCompetition(c *fiber.Ctx) (*CompetitionReq, error) {
+	var req CompetitionReq
+	if err := c.BodyParser(&req); err != nil {
+		return nil, util.NewError(c, fiber.StatusBadRequest, err)
+	}
+	if err := s.validator.Struct(&req); err != nil {
+		return nil, util.NewError(c, fiber.StatusBadRequest, err)
+	}
+	return &req, nil
+}
+
+// ListCompetitions godoc
+//
+// @Summary 	Get competitions of category
+// @Description Returns list of all competitions of the category
+// @Tags 		competitions
+// @Produce 	json
+// @Param category_id path int true "Category Id"
+// @Success 	200 		{array} 	model.Competition
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /categories/{category_id}/competitions [get]
+func (s *Server) ListCompetitions(c *fiber.Ctx) error {
+	category, err := s.findCategory(c)
+	if category == nil {
+		return err
+	}
+
+	var competitions []model.Competition
+	if err := s.DB.Where("category_id = ?", category.ID).Order("name, id").Find(&competitions).Error; err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(&fiber.Map{"data": competitions})
+}
+
+// CreateCompetition godoc
+//
+// @Summary 	Add a competition
+// @Description Creates new competition in the category and assigns unique Id
+// @Tags 		competitions
+// @Accept 		json
+// @Produce 	json
+// @Param category_id path int true "Category Id"
+// @Param competition body CompetitionReq true "Competition"
+// @Success 	201 		{object} 	model.Competition
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /categories/{category_id}/competitions [post]
+func (s *Server) CreateCompetition(c *fiber.Ctx) error {
+	category, err := s.findCategory(c)
+	if category == nil {
+		return err
+	}
+	req, err := s.parseCompetition(c)
+	if req == nil {
+		return err
+	}
+
+	competition := model.Competition{
+		CategoryID: category.ID,
+		Name:       req.Name,
+		Type:       req.Type,
+	}
+	if err := s.DB.Create(&competition).Error; err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(&fiber.Map{"data": competition})
+}
+
+// UpdateCompetition godoc
+//
+// @Summary 	Update a competition
+// @Description Changes name and type of the competition
+// @Tags 		competitions
+// @Accept 		json
+// @Produce 	json
+// @Param category_id path int true "Category Id"
+// @Param competition_id path int true "Competition Id"
+// @Param competition body CompetitionReq true "Competition"
+// @Success 	200 		{object} 	model.Competition
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /categories/{category_id}/competitions/{competition_id} [put]
+func (s *Server) UpdateCompetition(c *fiber.Ctx) error {
+	category, err := s.findCategory(c)
+	if category == nil {
+		return err
+	}
+	competition, err := s.findCompetition(c, category)
+	if competition == nil {
+		return err
+	}
+	req, err := s.parseCompetition(c)
+	if req == nil {
+		return err
+	}
+
+	competition.Name = req.Name
+	competition.Type = req.Type
+	if err := s.DB.Model(competition).Select("name", "type").Updates(competition).Error; err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(&fiber.Map{"data": competition})
+}
+
+// DeleteCompetition godoc
+//
+// @Summary 	Delete a competition
+// @Description Deletes competition without events
+// @Tags 		competitions
+// @Param category_id path int true "Category Id"
+// @Param competition_id path int true "Competition Id"
+// @Success 	204
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		409			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /categories/{category_id}/competitions/{competition_id} [delete]
+func (s *Server) DeleteCompetition(c *fiber.Ctx) error {
+	category, err := s.findCategory(c)
+	if category == nil {
+		return err
+	}
+	competition, err := s.findCompetition(c, category)
+	if competition == nil {
+		return err
+	}
+
+	// cached EventCount may lag behind, events are counted for real
+	var events int64
+	if err := s.DB.Model(&model.Event{}).Where("competition_id = ?", competition.ID).Count(&events).Error; err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	if events > 0 {
+		return util.NewErrorStr(c, fiber.StatusConflict, "competition has events")
+	}
+	if err := s.DB.Delete(competition).Error; err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/timadinorth/bet-exchange/model"
+)
+
+func (ts *ApiTestSuite) TestCompetitions() {
+	cookies := ts.signIn("tim")
+	db := ts.server.DB
+	category := model.Category{Name: "Soccer", Type: "sport"}
+	other := model.Category{Name: "Tennis", Type: "sport"}
+	db.Create(&category)
+	db.Create(&other)
+	url := fmt.Sprintf("/api/v1/categories/%d/competitions", category.ID)
+	var competition model.Competition
+
+	ts.T().Run("should require signin", func(t *testing.T) {
+		resp := ts.makeRequest("GET", url, nil)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	ts.T().Run("competition should be created in category", func(t *testing.T) {
+		resp := ts.makeAuthRequest("POST", url, CompetitionReq{Name: "Premier League", Type: "league"}, cookies)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		competition = parseData[model.Competition](t, resp)
+		assert.NotZero(t, competition.ID)
+		assert.Equal(t, category.ID, competition.CategoryID)
+		assert.Equal(t, "Premier League", competition.Name)
+	})
+
+	ts.T().Run("name should be provided in request", func(t *testing.T) {
+		resp := ts.makeAuthRequest("POST", url, CompetitionReq{Type: "league"}, cookies)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	ts.T().Run("unknown category should not be found", func(t *testing.T) {
+		resp := ts.makeAuthRequest("POST", fmt.Sprintf("/api/v1/categories/%d/competitions", other.ID+100),
+			CompetitionReq{Name: "Serie A"}, cookies)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	ts.T().Run("competitions should be listed by category", func(t *testing.T) {
+		ts.makeAuthRequest("POST", fmt.Sprintf("/api/v1/categories/%d/competitions", other.ID), CompetitionReq{Name: "Wimbledon"}, cookies)
+
+		resp := ts.makeAuthRequest("GET", url, nil, cookies)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		competitions := parseData[[]model.Competition](t, resp)
+		if assert.Len(t, competitions, 1) {
+			assert.Equal(t, competition.ID, competitions[0].ID)
+		}
+	})
+
+	ts.T().Run("competition should be updated", func(t *testing.T) {
+		resp := ts.makeAuthRequest("PUT", fmt.Sprintf("%s/%d", url, competition.ID), CompetitionReq{Name: "EPL", Type: "league"}, cookies)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "EPL", parseData[model.Competition](t, resp).Name)
+
+		resp = ts.makeAuthRequest("PUT", fmt.Sprintf("/api/v1/categories/%d/competitions/%d", other.ID, competition.ID),
+			CompetitionReq{Name: "EPL"}, cookies)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	ts.T().Run("event count should follow events", func(t *testing.T) {
+		event := model.Event{CompetitionID: competition.ID, Name: "Arsenal v Chelsea", ScheduledStart: time.Now()}
+		second := model.Event{CompetitionID: competition.ID, Name: "Everton v Fulham", ScheduledStart: time.Now()}
+		assert.Nil(t, db.Create(&event).Error)
+		assert.Nil(t, db.Create(&second).Error)
+		db.Take(&competition, competition.ID)
+		assert.Equal(t, 2, competition.EventCount)
+
+		assert.Nil(t, db.Delete(&second).Error)
+		db.Take(&competition, competition.ID)
+		assert.Equal(t, 1, competition.EventCount)
+	})
+
+	ts.T().Run("event count should follow moved events", func(t *testing.T) {
+		cup := ts.createCompetition("FA Cup")
+		var event model.Event
+		db.Where("competition_id = ?", competition.ID).Take(&event)
+
+		assert.Nil(t, db.Model(&event).Update("competition_id", cup.ID).Error)
+		db.Take(&competition, competition.ID)
+		db.Take(&cup, cup.ID)
+		assert.Equal(t, 0, competition.EventCount)
+		assert.Equal(t, 1, cup.EventCount)
+
+		event.CompetitionID = competition.ID
+		assert.Nil(t, db.Save(&event).Error)
+		db.Take(&competition, competition.ID)
+		db.Take(&cup, cup.ID)
+		assert.Equal(t, 1, competition.EventCount)
+		assert.Equal(t, 0, cup.EventCount)
+	})
+
+	ts.T().Run("event count should follow batch deletes", func(t *testing.T) {
+		cup := ts.createCompetition("League Cup")
+		for _, name := range []string{"Arsenal v Everton", "Chelsea v Fulham"} {
+			assert.Nil(t, db.Create(&model.Event{CompetitionID: cup.ID, Name: name, ScheduledStart: time.Now()}).Error)
+		}
+		assert.Nil(t, db.Where("competition_id = ?", cup.ID).Delete(&model.Event{}).Error)
+		db.Take(&cup, cup.ID)
+		assert.Equal(t, 0, cup.EventCount)
+		db.Take(&competition, competition.ID)
+		assert.Equal(t, 1, competition.EventCount)
+	})
+
+	ts.T().Run("competition with events should not be deleted", func(t *testing.T) {
+		resp := ts.makeAuthRequest("DELETE", fmt.Sprintf("%s/%d", url, competition.ID), nil, cookies)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		// stale cached count should not matter
+		db.Model(&competition).UpdateColumn("event_count", 0)
+		resp = ts.makeAuthRequest("DELETE", fmt.Sprintf("%s/%d", url, competition.ID), nil, cookies)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	ts.T().Run("competition without events should be deleted", func(t *testing.T) {
+		var event model.Event
+		db.Where("competition_id = ?", competition.ID).Take(&event)
+		assert.Nil(t, db.Delete(&event).Error)
+		db.Take(&competition, competition.ID)
+		assert.Equal(t, 0, competition.EventCount)
+
+		resp := ts.makeAuthRequest("DELETE", fmt.Sprintf("%s/%d", url, competition.ID), nil, cookies)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp = ts.makeAuthRequest("DELETE", fmt.Sprintf("%s/%d", url, competition.ID), nil, cookies)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
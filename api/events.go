@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/timadinorth/bet-exchange/model"
+	"github.com/timadinorth/bet-exchange/util"
+	"gorm.io/gorm"
+)
+
+// EventView - event with its markets and their current prices
+type EventView struct {
+	model.Event
+	Markets []MarketView `json:"markets"`
+}
+
+// ListEvents godoc
+//
+// @Summary 	Get events
+// @Description Returns page of events ordered by scheduled start
+// @Tags 		events
+// @Produce 	json
+// @Param category_id query int false "Category Id"
+// @Param competition_id query int false "Competition Id"
+// @Param status query string false "Event status" Enums(scheduled, in_play, finished, abandoned)
+// @Param from query string false "Scheduled start from, RFC 3339"
+// @Param to query string false "Scheduled start before, RFC 3339"
+// @Param in_play query bool false "In-play events only"
+// @Param limit query int false "Page size, 50 by default" maximum(200)
+// @Param cursor query string false "Cursor of the page returned by previous one"
+// @Success 	200 		{object} 	Page[model.Event]
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /events [get]
+func (s *Server) ListEvents(c *fiber.Ctx) error {
+	q, err := s.parseListQuery(c)
+	if q == nil {
+		return err
+	}
+	if q.Status != "" && !model.EventStatus(q.Status).Valid() {
+		return util.NewErrorStr(c, fiber.StatusBadRequest, "invalid event status")
+	}
+
+	db := s.DB.Model(&model.Event{}).Select("events.*")
+	if q.Status != "" {
+		db = db.Where("events.status = ?", q.Status)
+	}
+	if q.InPlay {
+		db = db.Where("events.status = ?", model.EventInPlay)
+	}
+
+	var events []model.Event
+	if err := q.filter(db, "events.id").Find(&events).Error; err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(page(events, q.Limit, func(e model.Event) cursor {
+		return cursor{Start: e.ScheduledStart, ID: e.ID}
+	}))
+}
+
+// GetEvent godoc
+//
+// @Summary 	Get an event
+// @Description Returns event with participants, outcomes and markets with best prices of their runners
+// @Tags 		events
+// @Produce 	json
+// @Param event_id path int true "Event Id"
+// @Success 	200 		{object} 	EventView
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /events/{event_id} [get]
+func (s *Server) GetEvent(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("event_id")
+	if err != nil || id <= 0 {
+		return util.NewErrorStr(c, fiber.StatusBadRequest, "invalid event id")
+	}
+
+	var event model.Event
+	if err := event.FindById(s.DB, uint(id)); errors.Is(err, gorm.ErrRecordNotFound) {
+		return util.NewErrorStr(c, fiber.StatusNotFound, "event not found")
+	} else if err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+
+	markets, err := model.FindEventMarkets(s.DB, event.ID)
+	if err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	view := EventView{Event: event, Markets: make([]MarketView, 0, len(markets))}
+	for i := range markets {
+		view.Markets = append(view.Markets, s.marketView(&markets[i]))
+	}
+	return c.Status(fiber.StatusOK).JSON(&fiber.Map{"data": view})
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/timadinorth/bet-exchange/exchange"
+	"github.com/timadinorth/bet-exchange/model"
+	"github.com/timadinorth/bet-exchange/orderbook"
+)
+
+func TestCursor(t *testing.T) {
+	c := cursor{Start: time.Date(2023, 8, 12, 15, 0, 0, 0, time.UTC), ID: 42}
+	parsed, err := parseCursor(c.String())
+	assert.Nil(t, err)
+	assert.True(t, parsed.Start.Equal(c.Start))
+	assert.Equal(t, c.ID, parsed.ID)
+
+	for _, s := range []string{"bad cursor", "e30"} {
+		_, err := parseCursor(s)
+		assert.Equal(t, errInvalidCursor, err, s)
+	}
+}
+
+func TestPage(t *testing.T) {
+	position := func(id int) cursor { return cursor{ID: uint(id)} }
+
+	p := page([]int{1, 2, 3}, 2, position)
+	assert.Equal(t, []int{1, 2}, p.Data)
+	assert.Equal(t, cursor{ID: 2}.String(), p.NextCursor)
+
+	p = page([]int{1, 2}, 2, position)
+	assert.Equal(t, []int{1, 2}, p.Data)
+	assert.Empty(t, p.NextCursor)
+
+	assert.NotNil(t, page(nil, 2, position).Data)
+}
+
+func (ts *ApiTestSuite) TestEventsAndMarkets() {
+	cookies := ts.signIn("tim")
+	db := ts.server.DB
+	football := ts.createCompetition("Premier League")
+	tennis := ts.createCompetition("Wimbledon")
+
+	start := time.Date(2023, 8, 12, 12, 0, 0, 0, time.UTC)
+	var events []model.Event
+	for i := 0; i < 5; i++ {
+		event := model.Event{
+			CompetitionID:  football.ID,
+			Name:           fmt.Sprintf("Event %d", i),
+			ScheduledStart: start.Add(time.Duration(4-i) * time.Hour),
+			Outcomes:       []model.Outcome{{Name: "Home", SortOrder: 1}, {Name: "Away", SortOrder: 2}},
+		}
+		if i == 4 {
+			event.CompetitionID = tennis.ID
+			event.Status = model.EventInPlay
+		}
+		if err := db.Create(&event).Error; err != nil {
+			ts.T().Fatal(err)
+		}
+		events = append(events, event)
+	}
+
+	market := model.Market{EventID: events[0].ID, Type: model.MatchOdds, Name: "Match Odds",
+		Runners: []model.Runner{{Name: "Home", SortOrder: 1}, {Name: "Away", SortOrder: 2}}}
+	inPlay := model.Market{EventID: events[4].ID, Type: model.MatchOdds, Name: "Match Odds", InPlay: true,
+		Runners: []model.Runner{{Name: "Home", SortOrder: 1}, {Name: "Away", SortOrder: 2}}}
+	db.Create(&market)
+	db.Create(&inPlay)
+	running, err := ts.server.Exchange.Add(&market)
+	if err != nil {
+		ts.T().Fatal(err)
+	}
+	o, _ := orderbook.NewOrder(orderbook.Back, decimal.NewFromFloat(2.5), decimal.NewFromInt(10))
+	res := running.Sequencer.Submit(orderbook.Command{Type: orderbook.PlaceCommand, Selection: exchange.Selection(market.Runners[0].ID), Order: o})
+	assert.Nil(ts.T(), res.Err)
+
+	list := func(t *testing.T, path string) Page[model.Event] {
+		resp := ts.makeAuthRequest("GET", path, nil, cookies)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return parseBody[Page[model.Event]](t, resp)
+	}
+
+	ts.T().Run("events should be paged in order of start", func(t *testing.T) {
+		first := list(t, "/api/v1/events?limit=3")
+		if assert.Len(t, first.Data, 3) {
+			assert.Equal(t, events[4].ID, first.Data[0].ID)
+			assert.Equal(t, events[2].ID, first.Data[2].ID)
+		}
+		assert.NotEmpty(t, first.NextCursor)
+
+		second := list(t, "/api/v1/events?limit=3&cursor="+first.NextCursor)
+		if assert.Len(t, second.Data, 2) {
+			assert.Equal(t, events[1].ID, second.Data[0].ID)
+			assert.Equal(t, events[0].ID, second.Data[1].ID)
+		}
+		assert.Empty(t, second.NextCursor)
+	})
+
+	ts.T().Run("events should be filtered", func(t *testing.T) {
+		assert.Len(t, list(t, fmt.Sprintf("/api/v1/events?competition_id=%d", football.ID)).Data, 4)
+		assert.Len(t, list(t, fmt.Sprintf("/api/v1/events?category_id=%d", tennis.CategoryID)).Data, 1)
+		assert.Len(t, list(t, "/api/v1/events?in_play=true").Data, 1)
+		assert.Len(t, list(t, "/api/v1/events?status=scheduled").Data, 4)
+
+		window := url.Values{
+			"from": {start.Add(time.Hour).Format(time.RFC3339)},
+			"to":   {start.Add(3 * time.Hour).Format(time.RFC3339)},
+		}
+		assert.Len(t, list(t, "/api/v1/events?"+window.Encode()).Data, 2)
+	})
+
+	ts.T().Run("malformed filters should not be allowed", func(t *testing.T) {
+		for _, query := range []string{"status=postponed", "from=yesterday", "limit=500", "cursor=bad"} {
+			resp := ts.makeAuthRequest("GET", "/api/v1/events?"+query, nil, cookies)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+
+	ts.T().Run("event should be returned with markets and prices", func(t *testing.T) {
+		resp := ts.makeAuthRequest("GET", fmt.Sprintf("/api/v1/events/%d", events[0].ID), nil, cookies)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		view := parseData[EventView](t, resp)
+		assert.Len(t, view.Outcomes, 2)
+		if assert.Len(t, view.Markets, 1) && assert.Len(t, view.Markets[0].Runners, 2) {
+			// resting back is offered to layers
+			assert.Nil(t, view.Markets[0].Runners[0].Back)
+			lay := view.Markets[0].Runners[0].Lay
+			if assert.NotNil(t, lay) {
+				assert.Equal(t, o.Price, lay.Price)
+			}
+		}
+
+		resp = ts.makeAuthRequest("GET", "/api/v1/events/100000", nil, cookies)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	ts.T().Run("markets should be filtered", func(t *testing.T) {
+		resp := ts.makeAuthRequest("GET", "/api/v1/markets?in_play=true", nil, cookies)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		markets := parseBody[Page[model.Market]](t, resp).Data
+		if assert.Len(t, markets, 1) {
+			assert.Equal(t, inPlay.ID, markets[0].ID)
+			assert.Len(t, markets[0].Runners, 2)
+		}
+
+		resp = ts.makeAuthRequest("GET", fmt.Sprintf("/api/v1/markets?competition_id=%d", football.ID), nil, cookies)
+		markets = parseBody[Page[model.Market]](t, resp).Data
+		if assert.Len(t, markets, 1) {
+			assert.Equal(t, market.ID, markets[0].ID)
+		}
+	})
+
+	ts.T().Run("market should be returned with best prices", func(t *testing.T) {
+		resp := ts.makeAuthRequest("GET", fmt.Sprintf("/api/v1/markets/%d", market.ID), nil, cookies)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		view := parseData[MarketView](t, resp)
+		if assert.Len(t, view.Runners, 2) {
+			assert.NotNil(t, view.Runners[0].Lay)
+			assert.Nil(t, view.Runners[0].Back)
+			assert.Nil(t, view.Runners[1].Lay)
+		}
+
+		resp = ts.makeAuthRequest("GET", fmt.Sprintf("/api/v1/markets/%d", inPlay.ID), nil, cookies)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Nil(t, parseData[MarketView](t, resp).Runners[0].Lay)
+	})
+}
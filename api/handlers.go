@@ -175,8 +175,3 @@ func (s *Server) ListCategories(c *fiber.Ctx) error {
 	s.DB.Find(&categories)
 	return c.Status(fiber.StatusOK).JSON(&fiber.Map{"data": categories})
 }
-
-// func (s *Server) ListCompetitions(c *gin.Context) {
-// 	categoryId := c.Param("category_id")
-// 	c.JSON(http.StatusOK, gin.H{"data": categoryId}) // FIXME
-// }
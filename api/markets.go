@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/timadinorth/bet-exchange/exchange"
+	"github.com/timadinorth/bet-exchange/model"
+	"github.com/timadinorth/bet-exchange/orderbook"
+	"github.com/timadinorth/bet-exchange/util"
+	"gorm.io/gorm"
+)
+
+// RunnerView - runner with best prices available to bettors, cross-matched
+// liquidity included. Back is the best price to back at, offered by resting
+// lays, and Lay the best price to lay at, offered by resting backs. Levels
+// are missing when nothing is offered or the market is not trading
+type RunnerView struct {
+	model.Runner
+	Back *orderbook.Level `json:"back,omitempty"`
+	Lay  *orderbook.Level `json:"lay,omitempty"`
+}
+
+// MarketView - market with current prices of its runners
+type MarketView struct {
+	model.Market
+	Runners []RunnerView `json:"runners"`
+}
+
+// marketView - reads best prices of the market runners from the exchange
+func (s *Server) marketView(m *model.Market) MarketView {
+	view := MarketView{Market: *m, Runners: make([]RunnerView, 0, len(m.Runners))}
+	for _, r := range m.Runners {
+		view.Runners = append(view.Runners, RunnerView{Runner: r})
+	}
+
+	running, err := s.Exchange.Market(m.ID)
+	if err != nil {
+		return view
+	}
+	// stopped sequencer leaves runners without prices
+	_ = running.Sequencer.View(func(book *orderbook.Market) {
+		for i := range view.Runners {
+			depth, err := book.Depth(exchange.Selection(view.Runners[i].ID), 1)
+			if err != nil {
+				continue
+			}
+			if len(depth.Lay) > 0 {
+				view.Runners[i].Back = &depth.Lay[0]
+			}
+			if len(depth.Back) > 0 {
+				view.Runners[i].Lay = &depth.Back[0]
+			}
+		}
+	})
+	return view
+}
+
+// ListMarkets godoc
+//
+// @Summary 	Get markets
+// @Description Returns page of markets with their runners ordered by scheduled start of the event
+// @Tags 		markets
+// @Produce 	json
+// @Param category_id query int false "Category Id"
+// @Param competition_id query int false "Competition Id"
+// @Param event_id query int false "Event Id"
+// @Param status query string false "Market status" Enums(open, suspended, closed, settled)
+// @Param from query string false "Scheduled start of the event from, RFC 3339"
+// @Param to query string false "Scheduled start of the event before, RFC 3339"
+// @Param in_play query bool false "In-play markets only"
+// @Param limit query int false "Page size, 50 by default" maximum(200)
+// @Param cursor query string false "Cursor of the page returned by previous one"
+// @Success 	200 		{object} 	Page[model.Market]
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /markets [get]
+func (s *Server) ListMarkets(c *fiber.Ctx) error {
+	q, err := s.parseListQuery(c)
+	if q == nil {
+		return err
+	}
+	if q.Status != "" && !model.MarketStatus(q.Status).Valid() {
+		return util.NewErrorStr(c, fiber.StatusBadRequest, "invalid market status")
+	}
+
+	db := s.DB.Model(&model.Market{}).Select("markets.*").
+		Joins("JOIN events ON events.id = markets.event_id AND events.deleted_at IS NULL").
+		Preload("Event").
+		Preload("Runners", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") })
+	if q.Status != "" {
+		db = db.Where("markets.status = ?", q.Status)
+	}
+	if q.InPlay {
+		db = db.Where("markets.in_play")
+	}
+
+	var markets []model.Market
+	if err := q.filter(db, "markets.id").Find(&markets).Error; err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(page(markets, q.Limit, func(m model.Market) cursor {
+		return cursor{Start: m.Event.ScheduledStart, ID: m.ID}
+	}))
+}
+
+// GetMarket godoc
+//
+// @Summary 	Get a market
+// @Description Returns market with its event and best back and lay prices of its runners
+// @Tags 		markets
+// @Produce 	json
+// @Param market_id path int true "Market Id"
+// @Success 	200 		{object} 	MarketView
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /markets/{market_id} [get]
+func (s *Server) GetMarket(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("market_id")
+	if err != nil || id <= 0 {
+		return util.NewErrorStr(c, fiber.StatusBadRequest, "invalid market id")
+	}
+
+	var market model.Market
+	if err := market.FindById(s.DB.Preload("Event"), uint(id)); errors.Is(err, gorm.ErrRecordNotFound) {
+		return util.NewErrorStr(c, fiber.StatusNotFound, "market not found")
+	} else if err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(&fiber.Map{"data": s.marketView(&market)})
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/timadinorth/bet-exchange/exchange"
+	"github.com/timadinorth/bet-exchange/model"
+)
+
+// createCompetition - creates competition in a new category
+func (ts *ApiTestSuite) createCompetition(name string) model.Competition {
+	category := model.Category{Name: "Football", Type: "sport"}
+	if err := ts.server.DB.Create(&category).Error; err != nil {
+		ts.T().Fatal(err)
+	}
+	competition := model.Competition{CategoryID: category.ID, Name: name, Type: "league"}
+	if err := ts.server.DB.Create(&competition).Error; err != nil {
+		ts.T().Fatal(err)
+	}
+	return competition
+}
+
+func (ts *ApiTestSuite) TestEventRelations() {
+	db := ts.server.DB
+	competition := ts.createCompetition("Premier League")
+
+	event := model.Event{
+		CompetitionID:  competition.ID,
+		Name:           "Arsenal v Chelsea",
+		ScheduledStart: time.Date(2023, 8, 12, 15, 0, 0, 0, time.UTC),
+		Venue:          "Emirates Stadium",
+		ExternalId:     "feed-1001",
+		Participants: []model.Participant{
+			{Name: "Chelsea", Role: "away", SortOrder: 2},
+			{Name: "Arsenal", Role: "home", SortOrder: 1},
+		},
+		Outcomes: []model.Outcome{
+			{Name: "Chelsea", SortOrder: 3},
+			{Name: "Draw", SortOrder: 2},
+			{Name: "Arsenal -1.5", SortOrder: 1, Handicap: decimal.NewFromFloat(-1.5)},
+		},
+	}
+
+	ts.T().Run("event should be created with participants and outcomes", func(t *testing.T) {
+		assert.Nil(t, db.Create(&event).Error)
+		assert.Equal(t, model.EventScheduled, event.Status)
+	})
+
+	ts.T().Run("event should be loaded with children in sort order", func(t *testing.T) {
+		var loaded model.Event
+		assert.Nil(t, loaded.FindById(db, event.ID))
+		assert.Equal(t, competition.ID, loaded.CompetitionID)
+		assert.Equal(t, "feed-1001", loaded.ExternalId)
+		assert.True(t, loaded.ScheduledStart.Equal(event.ScheduledStart))
+
+		if assert.Len(t, loaded.Participants, 2) {
+			assert.Equal(t, "Arsenal", loaded.Participants[0].Name)
+			assert.Equal(t, "Chelsea", loaded.Participants[1].Name)
+		}
+		if assert.Len(t, loaded.Outcomes, 3) {
+			assert.Equal(t, "Arsenal -1.5", loaded.Outcomes[0].Name)
+			assert.True(t, loaded.Outcomes[0].Handicap.Equal(decimal.NewFromFloat(-1.5)))
+			assert.Equal(t, "Draw", loaded.Outcomes[1].Name)
+			assert.True(t, loaded.Outcomes[1].Handicap.IsZero())
+		}
+	})
+
+	ts.T().Run("event should belong to existing competition", func(t *testing.T) {
+		orphan := model.Event{CompetitionID: competition.ID + 100, Name: "Orphan", ScheduledStart: time.Now()}
+		assert.NotNil(t, db.Create(&orphan).Error)
+	})
+
+	ts.T().Run("outcomes should be removed with event", func(t *testing.T) {
+		assert.Nil(t, db.Unscoped().Delete(&event).Error)
+
+		var count int64
+		db.Unscoped().Model(&model.Outcome{}).Where("event_id = ?", event.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+		db.Unscoped().Model(&model.Participant{}).Where("event_id = ?", event.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestEventStatusValid(t *testing.T) {
+	for _, status := range []model.EventStatus{model.EventScheduled, model.EventInPlay, model.EventFinished, model.EventAbandoned} {
+		assert.True(t, status.Valid(), status)
+	}
+	assert.False(t, model.EventStatus("postponed").Valid())
+}
+
+func (ts *ApiTestSuite) TestMarketRunners() {
+	db := ts.server.DB
+	competition := ts.createCompetition("Premier League")
+	event := model.Event{
+		CompetitionID:  competition.ID,
+		Name:           "Arsenal v Chelsea",
+		ScheduledStart: time.Now().Add(time.Hour),
+		Outcomes:       []model.Outcome{{Name: "Arsenal", SortOrder: 1}, {Name: "Draw", SortOrder: 2}, {Name: "Chelsea", SortOrder: 3}},
+	}
+	if err := db.Create(&event).Error; err != nil {
+		ts.T().Fatal(err)
+	}
+
+	market := model.Market{
+		EventID:        event.ID,
+		Type:           model.MatchOdds,
+		Name:           "Match Odds",
+		BetDelay:       5,
+		CommissionRate: decimal.NewFromFloat(0.05),
+	}
+	for _, o := range event.Outcomes {
+		id := o.ID
+		market.Runners = append(market.Runners, model.Runner{OutcomeID: &id, Name: o.Name, SortOrder: o.SortOrder})
+	}
+	closed := model.Market{EventID: event.ID, Type: model.OverUnder, Name: "Over/Under 2.5 Goals", Line: decimal.NewFromFloat(2.5), Status: model.MarketClosed,
+		Runners: []model.Runner{{Name: "Over 2.5"}, {Name: "Under 2.5"}}}
+
+	ts.T().Run("market should be created with runners", func(t *testing.T) {
+		assert.Nil(t, db.Create(&market).Error)
+		assert.Nil(t, db.Create(&closed).Error)
+		assert.Equal(t, model.MarketOpen, market.Status)
+
+		var loaded model.Market
+		assert.Nil(t, loaded.FindById(db, market.ID))
+		assert.True(t, loaded.CommissionRate.Equal(decimal.NewFromFloat(0.05)))
+		if assert.Len(t, loaded.Runners, 3) {
+			assert.Equal(t, "Draw", loaded.Runners[1].Name)
+			assert.Equal(t, event.Outcomes[1].ID, *loaded.Runners[1].OutcomeID)
+		}
+	})
+
+	ts.T().Run("registry should load orderbooks of trading markets", func(t *testing.T) {
+		ts.server.Exchange.Close()
+		ts.server.InitExchange()
+
+		m, err := ts.server.Exchange.Market(market.ID)
+		assert.Nil(t, err)
+		assert.Len(t, m.Book.Selections(), 3)
+		assert.Equal(t, 5*time.Second, m.Book.BetDelay)
+
+		_, _, err = ts.server.Exchange.Book(market.Runners[2].ID)
+		assert.Nil(t, err)
+		_, err = ts.server.Exchange.Market(closed.ID)
+		assert.Equal(t, exchange.ErrMarketNotFound, err)
+	})
+}
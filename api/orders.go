@@ -0,0 +1,425 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"github.com/timadinorth/bet-exchange/exchange"
+	"github.com/timadinorth/bet-exchange/model"
+	"github.com/timadinorth/bet-exchange/orderbook"
+	"github.com/timadinorth/bet-exchange/util"
+	"gorm.io/gorm"
+)
+
+type PlaceOrderReq struct {
+	RunnerID uint            `json:"runner_id" validate:"required" example:"1"`
+	Side     model.OrderSide `json:"side" validate:"required,oneof=back lay" example:"back"`
+	Price    decimal.Decimal `json:"price" example:"2.5"`
+	Stake    decimal.Decimal `json:"stake" example:"10"`
+}
+
+// AmendOrderReq - new price and unmatched stake of the order, omitted ones
+// are kept
+type AmendOrderReq struct {
+	Price decimal.Decimal `json:"price" example:"3"`
+	Stake decimal.Decimal `json:"stake" example:"5"`
+}
+
+// OrdersQuery - open orders have unmatched stake, matched ones have at least
+// one fill and history holds orders which are over
+type OrdersQuery struct {
+	Status   string `query:"status" validate:"omitempty,oneof=open matched history"`
+	MarketID uint   `query:"market_id"`
+	Limit    int    `query:"limit" validate:"min=0,max=200"`
+	Cursor   string `query:"cursor"`
+}
+
+// orderError - responds with status matching the error of the exchange
+func orderError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, orderbook.ErrInvalidStake),
+		errors.Is(err, orderbook.ErrInvalidOrderPrice),
+		errors.Is(err, orderbook.ErrPriceNotOnLadder),
+		errors.Is(err, orderbook.ErrInsufficientFunds):
+		status = fiber.StatusBadRequest
+	case errors.Is(err, exchange.ErrRunnerNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, exchange.ErrMarketNotFound),
+		errors.Is(err, orderbook.ErrOrderNotFound),
+		errors.Is(err, orderbook.ErrMarketSuspended),
+		errors.Is(err, orderbook.ErrMarketClosed),
+		errors.Is(err, orderbook.ErrSequencerStopped):
+		status = fiber.StatusConflict
+	}
+	return util.NewError(c, status, err)
+}
+
+// orderStatus - status of the order in the orderbook
+func orderStatus(status orderbook.OrderStatus) model.OrderStatus {
+	return [...]model.OrderStatus{
+		model.OrderPending,
+		model.OrderResting,
+		model.OrderFilled,
+		model.OrderCancelled,
+		model.OrderKilled,
+	}[status]
+}
+
+// currentUser - loads user of the session
+func (s *Server) currentUser(c *fiber.Ctx) (*model.User, error) {
+	session, err := s.Session.Get(c)
+	if err != nil {
+		return nil, util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	username, _ := session.Get("username").(string)
+
+	var user model.User
+	if err := user.FindByUsername(s.DB, username); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, c.SendStatus(fiber.StatusUnauthorized)
+	} else if err != nil {
+		return nil, util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return &user, nil
+}
+
+// findOrder - loads open order of the user from id parameter, responds with
+// error status when it can not be found or changed
+func (s *Server) findOrder(c *fiber.Ctx, user *model.User) (*model.Order, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return nil, util.NewErrorStr(c, fiber.StatusBadRequest, "invalid order id")
+	}
+
+	var order model.Order
+	err = s.DB.Preload("Fills", func(db *gorm.DB) *gorm.DB { return db.Order("seq") }).
+		Where("user_id = ?", user.ID).Take(&order, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, util.NewErrorStr(c, fiber.StatusNotFound, "order not found")
+	} else if err != nil {
+		return nil, util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	if !order.Status.Open() {
+		return nil, util.NewErrorStr(c, fiber.StatusConflict, "order is not open")
+	}
+	return &order, nil
+}
+
+// record - persists result of the command submitted for the order: its new
+// state, its fills and fills of the resting orders it was matched with.
+// Has to run inside Exclusive of the market, so that results are persisted
+// in the order they were applied
+func (s *Server) record(order *model.Order, res orderbook.Result) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		selection := exchange.Selection(order.RunnerID)
+		var fills []model.Fill
+		for _, t := range res.Trades {
+			fill := model.Fill{Seq: t.Seq, Price: t.Price.Decimal(), Stake: t.Stake.Decimal()}
+			if t.MakerId != "" {
+				if err := recordMaker(tx, t.MakerId, fill); err != nil {
+					return err
+				}
+			}
+			// legs of cross-matched order on other selections are fills
+			// of their makers only
+			if t.TakerId == order.BookId && t.Selection == selection {
+				fills = append(fills, fill)
+				order.Matched = order.Matched.Add(fill.Stake)
+			}
+		}
+
+		order.Price = res.Order.Price.Decimal()
+		order.Remaining = res.Order.Stake.Decimal()
+		order.Stake = order.Matched.Add(order.Remaining)
+		order.Status = orderStatus(res.Order.Status)
+		if err := tx.Omit("Fills").Save(order).Error; err != nil {
+			return err
+		}
+
+		for i := range fills {
+			fills[i].OrderID = order.ID
+		}
+		if len(fills) > 0 {
+			if err := tx.Create(&fills).Error; err != nil {
+				return err
+			}
+		}
+		order.Fills = append(order.Fills, fills...)
+		return nil
+	})
+}
+
+// placed - records result of the order placed outside of a request, e.g.
+// released after bet delay. Order rejected by the market is cancelled. Runs
+// inside Exclusive of the market
+func (s *Server) placed(market *exchange.Market, res orderbook.Result) {
+	var order model.Order
+	err := order.FindByBookId(s.DB, res.Order.Id)
+	if err == nil {
+		if res.Err != nil {
+			res.Order.Status = orderbook.Cancelled
+		}
+		err = s.record(&order, res)
+	}
+	if err != nil {
+		s.Log.Errorf("Failed to record order %s: %v", res.Order.Id, err)
+	}
+}
+
+// recordMaker - adds fill to the resting order it was matched with
+func recordMaker(tx *gorm.DB, bookId string, fill model.Fill) error {
+	var maker model.Order
+	if err := maker.FindByBookId(tx, bookId); err != nil {
+		return err
+	}
+
+	maker.Matched = maker.Matched.Add(fill.Stake)
+	maker.Remaining = maker.Remaining.Sub(fill.Stake)
+	if maker.Remaining.Sign() <= 0 {
+		maker.Remaining = decimal.Zero
+		maker.Status = model.OrderFilled
+	}
+	if err := tx.Save(&maker).Error; err != nil {
+		return err
+	}
+
+	fill.OrderID = maker.ID
+	return tx.Create(&fill).Error
+}
+
+// PlaceOrder godoc
+//
+// @Summary 	Place an order
+// @Description Places order on the runner, returns its fills and remaining stake
+// @Tags 		orders
+// @Accept 		json
+// @Produce 	json
+// @Param order body PlaceOrderReq true "Order"
+// @Success 	201 		{object} 	model.Order
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		409			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /orders [post]
+func (s *Server) PlaceOrder(c *fiber.Ctx) error {
+	user, err := s.currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var req PlaceOrderReq
+	if err := c.BodyParser(&req); err != nil {
+		return util.NewError(c, fiber.StatusBadRequest, err)
+	}
+	if err := s.validator.Struct(&req); err != nil {
+		return util.NewError(c, fiber.StatusBadRequest, err)
+	}
+
+	side := orderbook.Back
+	if req.Side == model.Lay {
+		side = orderbook.Lay
+	}
+	o, err := orderbook.NewOrder(side, req.Price, req.Stake)
+	if err != nil {
+		return orderError(c, err)
+	}
+	o.Owner = exchange.Owner(user.ID)
+
+	market, _, err := s.Exchange.Book(req.RunnerID)
+	if err != nil {
+		return orderError(c, err)
+	}
+
+	order := model.Order{
+		UserID:   user.ID,
+		MarketID: market.ID,
+		RunnerID: req.RunnerID,
+		BookId:   o.Id,
+		Side:     req.Side,
+	}
+	err = market.Exclusive(func() error {
+		res := market.Submit(orderbook.Command{
+			Type:      orderbook.PlaceCommand,
+			Selection: exchange.Selection(req.RunnerID),
+			Order:     o,
+		})
+		if res.Err != nil {
+			return res.Err
+		}
+		return s.record(&order, res)
+	})
+	if err != nil {
+		return orderError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(&fiber.Map{"data": order})
+}
+
+// CancelOrder godoc
+//
+// @Summary 	Cancel an order
+// @Description Cancels unmatched stake of the order
+// @Tags 		orders
+// @Produce 	json
+// @Param id path int true "Order Id"
+// @Success 	200 		{object} 	model.Order
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		409			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /orders/{id} [delete]
+func (s *Server) CancelOrder(c *fiber.Ctx) error {
+	user, err := s.currentUser(c)
+	if user == nil {
+		return err
+	}
+	order, err := s.findOrder(c, user)
+	if order == nil {
+		return err
+	}
+
+	market, err := s.Exchange.Market(order.MarketID)
+	if err != nil {
+		return orderError(c, err)
+	}
+	err = market.Exclusive(func() error {
+		res := market.Submit(orderbook.Command{Type: orderbook.CancelCommand, OrderId: order.BookId})
+		if res.Err != nil {
+			return res.Err
+		}
+		return s.record(order, res)
+	})
+	if err != nil {
+		return orderError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(&fiber.Map{"data": order})
+}
+
+// AmendOrder godoc
+//
+// @Summary 	Amend an order
+// @Description Changes price and unmatched stake of the order, new price may be matched at once
+// @Tags 		orders
+// @Accept 		json
+// @Produce 	json
+// @Param id path int true "Order Id"
+// @Param order body AmendOrderReq true "New price and unmatched stake"
+// @Success 	200 		{object} 	model.Order
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		404			{object}	util.HTTPError
+// @Failure		409			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /orders/{id} [patch]
+func (s *Server) AmendOrder(c *fiber.Ctx) error {
+	user, err := s.currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var req AmendOrderReq
+	if err := c.BodyParser(&req); err != nil {
+		return util.NewError(c, fiber.StatusBadRequest, err)
+	}
+	if req.Price.IsZero() && req.Stake.IsZero() {
+		return util.NewErrorStr(c, fiber.StatusBadRequest, "price or stake should be provided")
+	}
+
+	order, err := s.findOrder(c, user)
+	if order == nil {
+		return err
+	}
+	if req.Price.IsZero() {
+		req.Price = order.Price
+	}
+	if req.Stake.IsZero() {
+		req.Stake = order.Remaining
+	}
+
+	market, err := s.Exchange.Market(order.MarketID)
+	if err != nil {
+		return orderError(c, err)
+	}
+	err = market.Exclusive(func() error {
+		res := market.Submit(orderbook.Command{
+			Type:    orderbook.AmendCommand,
+			OrderId: order.BookId,
+			Price:   req.Price,
+			Amount:  req.Stake,
+		})
+		if res.Err != nil {
+			return res.Err
+		}
+		return s.record(order, res)
+	})
+	if err != nil {
+		return orderError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(&fiber.Map{"data": order})
+}
+
+// ListOrders godoc
+//
+// @Summary 	Get orders
+// @Description Returns page of orders of the user, newest first
+// @Tags 		orders
+// @Produce 	json
+// @Param status query string false "Open by default" Enums(open, matched, history)
+// @Param market_id query int false "Market Id"
+// @Param limit query int false "Page size, 50 by default" maximum(200)
+// @Param cursor query string false "Cursor of the page returned by previous one"
+// @Success 	200 		{object} 	Page[model.Order]
+// @Failure		400			{object}	util.HTTPError
+// @Failure		401			{object}	util.HTTPError
+// @Failure		500			{object}	util.HTTPError
+// @Router      /orders [get]
+func (s *Server) ListOrders(c *fiber.Ctx) error {
+	user, err := s.currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var q OrdersQuery
+	if err := c.QueryParser(&q); err != nil {
+		return util.NewError(c, fiber.StatusBadRequest, err)
+	}
+	if err := s.validator.Struct(&q); err != nil {
+		return util.NewError(c, fiber.StatusBadRequest, err)
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultPageLimit
+	}
+
+	open := []model.OrderStatus{model.OrderPending, model.OrderResting}
+	db := s.DB.Model(&model.Order{}).
+		Preload("Fills", func(db *gorm.DB) *gorm.DB { return db.Order("seq") }).
+		Where("user_id = ?", user.ID)
+	switch q.Status {
+	case "", "open":
+		db = db.Where("status IN ?", open)
+	case "matched":
+		db = db.Where("matched > 0")
+	case "history":
+		db = db.Where("status NOT IN ?", open)
+	}
+	if q.MarketID != 0 {
+		db = db.Where("market_id = ?", q.MarketID)
+	}
+	if q.Cursor != "" {
+		after, err := parseCursor(q.Cursor)
+		if err != nil {
+			return util.NewError(c, fiber.StatusBadRequest, err)
+		}
+		db = db.Where("id < ?", after.ID)
+	}
+
+	var orders []model.Order
+	if err := db.Order("id desc").Limit(q.Limit + 1).Find(&orders).Error; err != nil {
+		return util.NewError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(page(orders, q.Limit, func(o model.Order) cursor {
+		return cursor{ID: o.ID}
+	}))
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/timadinorth/bet-exchange/model"
+	"github.com/timadinorth/bet-exchange/orderbook"
+)
+
+// createMarket - creates trading market with two runners and adds it to
+// the exchange
+func (ts *ApiTestSuite) createMarket() model.Market {
+	competition := ts.createCompetition("Premier League")
+	event := model.Event{CompetitionID: competition.ID, Name: "Arsenal v Chelsea", ScheduledStart: time.Now().Add(time.Hour)}
+	if err := ts.server.DB.Create(&event).Error; err != nil {
+		ts.T().Fatal(err)
+	}
+	market := model.Market{EventID: event.ID, Type: model.MatchOdds, Name: "Match Odds",
+		Runners: []model.Runner{{Name: "Arsenal", SortOrder: 1}, {Name: "Chelsea", SortOrder: 2}}}
+	if err := ts.server.DB.Create(&market).Error; err != nil {
+		ts.T().Fatal(err)
+	}
+	if _, err := ts.server.Exchange.Add(&market); err != nil {
+		ts.T().Fatal(err)
+	}
+	return market
+}
+
+func (ts *ApiTestSuite) TestOrders() {
+	layer := ts.signIn("tim")
+	backer := ts.signIn("adi")
+	market := ts.createMarket()
+	runner := market.Runners[0].ID
+	var lay model.Order
+
+	place := func(t *testing.T, cookies []*http.Cookie, req PlaceOrderReq) (*http.Response, model.Order) {
+		resp := ts.makeAuthRequest("POST", "/api/v1/orders", req, cookies)
+		if resp.StatusCode != http.StatusCreated {
+			return resp, model.Order{}
+		}
+		return resp, parseData[model.Order](t, resp)
+	}
+	list := func(t *testing.T, cookies []*http.Cookie, status string) []model.Order {
+		resp := ts.makeAuthRequest("GET", "/api/v1/orders?status="+status, nil, cookies)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return parseBody[Page[model.Order]](t, resp).Data
+	}
+
+	ts.T().Run("should require signin", func(t *testing.T) {
+		resp := ts.makeRequest("POST", "/api/v1/orders", PlaceOrderReq{RunnerID: runner, Side: model.Back})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	ts.T().Run("order should rest when there is nothing to match", func(t *testing.T) {
+		var resp *http.Response
+		resp, lay = place(t, layer, PlaceOrderReq{RunnerID: runner, Side: model.Lay,
+			Price: decimal.NewFromFloat(2.5), Stake: decimal.NewFromInt(10)})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, model.OrderResting, lay.Status)
+		assert.True(t, lay.Remaining.Equal(decimal.NewFromInt(10)))
+		assert.Empty(t, lay.Fills)
+	})
+
+	ts.T().Run("order should return its fills", func(t *testing.T) {
+		resp, back := place(t, backer, PlaceOrderReq{RunnerID: runner, Side: model.Back,
+			Price: decimal.NewFromFloat(2.5), Stake: decimal.NewFromInt(4)})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, model.OrderFilled, back.Status)
+		assert.True(t, back.Matched.Equal(decimal.NewFromInt(4)))
+		assert.True(t, back.Remaining.IsZero())
+		if assert.Len(t, back.Fills, 1) {
+			assert.True(t, back.Fills[0].Price.Equal(decimal.NewFromFloat(2.5)))
+		}
+	})
+
+	ts.T().Run("resting order should record its fills", func(t *testing.T) {
+		open := list(t, layer, "open")
+		if assert.Len(t, open, 1) {
+			assert.Equal(t, lay.ID, open[0].ID)
+			assert.True(t, open[0].Matched.Equal(decimal.NewFromInt(4)))
+			assert.True(t, open[0].Remaining.Equal(decimal.NewFromInt(6)))
+			assert.Len(t, open[0].Fills, 1)
+		}
+		assert.Len(t, list(t, layer, "matched"), 1)
+		assert.Empty(t, list(t, layer, "history"))
+		assert.Len(t, list(t, backer, "history"), 1)
+	})
+
+	ts.T().Run("malformed orders should not be allowed", func(t *testing.T) {
+		for _, req := range []PlaceOrderReq{
+			{RunnerID: runner, Side: "draw", Price: decimal.NewFromInt(2), Stake: decimal.NewFromInt(1)},
+			{RunnerID: runner, Side: model.Back, Price: decimal.NewFromInt(2), Stake: decimal.Zero},
+			{RunnerID: runner, Side: model.Back, Price: decimal.NewFromInt(1), Stake: decimal.NewFromInt(1)},
+			{RunnerID: runner, Side: model.Back, Price: decimal.NewFromFloat(2.01), Stake: decimal.NewFromFloat(1.001)},
+		} {
+			resp, _ := place(t, backer, req)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, req)
+		}
+
+		resp, _ := place(t, backer, PlaceOrderReq{RunnerID: runner + 100, Side: model.Back,
+			Price: decimal.NewFromInt(2), Stake: decimal.NewFromInt(1)})
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	ts.T().Run("order should be amended", func(t *testing.T) {
+		resp := ts.makeAuthRequest("PATCH", fmt.Sprintf("/api/v1/orders/%d", lay.ID), AmendOrderReq{Stake: decimal.NewFromInt(3)}, layer)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		amended := parseData[model.Order](t, resp)
+		assert.True(t, amended.Remaining.Equal(decimal.NewFromInt(3)))
+		assert.True(t, amended.Stake.Equal(decimal.NewFromInt(7)))
+		assert.True(t, amended.Price.Equal(decimal.NewFromFloat(2.5)))
+
+		resp = ts.makeAuthRequest("PATCH", fmt.Sprintf("/api/v1/orders/%d", lay.ID), AmendOrderReq{Price: decimal.NewFromFloat(2.51)}, layer)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	ts.T().Run("order of another user should not be found", func(t *testing.T) {
+		resp := ts.makeAuthRequest("DELETE", fmt.Sprintf("/api/v1/orders/%d", lay.ID), nil, backer)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	ts.T().Run("open order should be restored on restart", func(t *testing.T) {
+		ts.server.Exchange.Close()
+		ts.server.InitExchange()
+
+		m, ob, err := ts.server.Exchange.Book(runner)
+		if assert.Nil(t, err) {
+			var best orderbook.Price
+			assert.Nil(t, m.Sequencer.View(func(*orderbook.Market) { best = ob.BestLay() }))
+			assert.True(t, lay.Price.Equal(best.Decimal()))
+		}
+	})
+
+	ts.T().Run("order should be cancelled", func(t *testing.T) {
+		resp := ts.makeAuthRequest("DELETE", fmt.Sprintf("/api/v1/orders/%d", lay.ID), nil, layer)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, model.OrderCancelled, parseData[model.Order](t, resp).Status)
+
+		resp = ts.makeAuthRequest("DELETE", fmt.Sprintf("/api/v1/orders/%d", lay.ID), nil, layer)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Empty(t, list(t, layer, "open"))
+		assert.Len(t, list(t, layer, "history"), 1)
+	})
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/timadinorth/bet-exchange/util"
+	"gorm.io/gorm"
+)
+
+const defaultPageLimit = 50
+
+var errInvalidCursor = errors.New("api: invalid cursor")
+
+// ListQuery - filters and page of events and markets lists. Times are RFC
+// 3339, results are ordered by scheduled start of the event and id
+type ListQuery struct {
+	CategoryID    uint   `query:"category_id"`
+	CompetitionID uint   `query:"competition_id"`
+	EventID       uint   `query:"event_id"`
+	Status        string `query:"status"`
+	From          string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To            string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	InPlay        bool   `query:"in_play"`
+	Limit         int    `query:"limit" validate:"min=0,max=200"`
+	Cursor        string `query:"cursor"`
+
+	from, to time.Time
+	after    *cursor
+}
+
+// cursor - position after the last item of the page, opaque to clients
+type cursor struct {
+	Start time.Time `json:"s"`
+	ID    uint      `json:"i"`
+}
+
+func (c cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func parseCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 {
+		return nil, errInvalidCursor
+	}
+	return &c, nil
+}
+
+// Page - items of the list and cursor of the next page, empty on the last one
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// parseListQuery - parses and validates list query, responds with error
+// status when it is malformed
+func (s *Server) parseListQuery(c *fiber.Ctx) (*ListQuery, error) {
+	var q ListQuery
+	if err := c.QueryParser(&q); err != nil {
+		return nil, util.NewError(c, fiber.StatusBadRequest, err)
+	}
+	if err := s.validator.Struct(&q); err != nil {
+		return nil, util.NewError(c, fiber.StatusBadRequest, err)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultPageLimit
+	}
+	if q.From != "" {
+		q.from, _ = time.Parse(time.RFC3339, q.From)
+	}
+	if q.To != "" {
+		q.to, _ = time.Parse(time.RFC3339, q.To)
+	}
+	if q.Cursor != "" {
+		after, err := parseCursor(q.Cursor)
+		if err != nil {
+			return nil, util.NewError(c, fiber.StatusBadRequest, err)
+		}
+		q.after = after
+	}
+	return &q, nil
+}
+
+// filter - applies filters of the query but status and in play ones, which
+// differ between events and markets. Events table has to be in the query and
+// idColumn is the column the list is ordered by after scheduled start
+func (q *ListQuery) filter(db *gorm.DB, idColumn string) *gorm.DB {
+	if q.CategoryID != 0 {
+		db = db.Joins("JOIN competitions ON competitions.id = events.competition_id AND competitions.deleted_at IS NULL").
+			Where("competitions.category_id = ?", q.CategoryID)
+	}
+	if q.CompetitionID != 0 {
+		db = db.Where("events.competition_id = ?", q.CompetitionID)
+	}
+	if q.EventID != 0 {
+		db = db.Where("events.id = ?", q.EventID)
+	}
+	if !q.from.IsZero() {
+		db = db.Where("events.scheduled_start >= ?", q.from)
+	}
+	if !q.to.IsZero() {
+		db = db.Where("events.scheduled_start < ?", q.to)
+	}
+	if q.after != nil {
+		db = db.Where("(events.scheduled_start, "+idColumn+") > (?, ?)", q.after.Start, q.after.ID)
+	}
+	return db.Order("events.scheduled_start, " + idColumn).Limit(q.Limit + 1)
+}
+
+// page - cuts items fetched with one extra row to the limit and sets cursor
+// of the next page when there are more
+func page[T any](items []T, limit int, position func(T) cursor) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) <= limit {
+		return Page[T]{Data: items}
+	}
+	items = items[:limit]
+	return Page[T]{Data: items, NextCursor: position(items[limit-1]).String()}
+}
@@ -34,10 +34,18 @@ func (s *Server) RegisterRoutes() {
 	auth.Post("/signout", s.SignOut)
 	v1.Get("/categories", s.ListCategories)
 	v1.Post("/categories", s.CreateCategory)
-	// {
-	// 	//
-	// 	// 	v1.GET("/categories/:category_id/competitions", s.ListCompetitions)
-	// }
+	v1.Get("/categories/:category_id/competitions", s.ListCompetitions)
+	v1.Post("/categories/:category_id/competitions", s.CreateCompetition)
+	v1.Put("/categories/:category_id/competitions/:competition_id", s.UpdateCompetition)
+	v1.Delete("/categories/:category_id/competitions/:competition_id", s.DeleteCompetition)
+	v1.Get("/events", s.ListEvents)
+	v1.Get("/events/:event_id", s.GetEvent)
+	v1.Get("/markets", s.ListMarkets)
+	v1.Get("/markets/:market_id", s.GetMarket)
+	v1.Get("/orders", s.ListOrders)
+	v1.Post("/orders", s.PlaceOrder)
+	v1.Patch("/orders/:id", s.AmendOrder)
+	v1.Delete("/orders/:id", s.CancelOrder)
 	app.Use(func(c *fiber.Ctx) error {
 		return c.SendStatus(404)
 	})
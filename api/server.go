@@ -12,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	_ "github.com/timadinorth/bet-exchange/docs"
+	"github.com/timadinorth/bet-exchange/exchange"
 	"github.com/timadinorth/bet-exchange/model"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -38,6 +39,7 @@ type Server struct {
 	Config    *Config
 	Cache     *redis.Client
 	Session   *session.Store
+	Exchange  *exchange.Registry
 	validator *validator.Validate
 }
 
@@ -94,11 +96,21 @@ func (s *Server) ConnectDB() {
 }
 
 func (s *Server) SetupModels() error {
-	return s.DB.AutoMigrate(&model.Category{}, &model.Competition{}, &model.User{})
+	return s.DB.AutoMigrate(&model.Category{}, &model.Competition{}, &model.Event{}, &model.Participant{}, &model.Outcome{}, &model.Market{}, &model.Runner{}, &model.User{}, &model.Order{}, &model.Fill{})
 }
 
 func (s *Server) CleanupModels() error {
-	return s.DB.Migrator().DropTable(&model.Category{}, &model.Competition{}, &model.User{})
+	return s.DB.Migrator().DropTable(&model.Fill{}, &model.Order{}, &model.Runner{}, &model.Market{}, &model.Outcome{}, &model.Participant{}, &model.Event{}, &model.Competition{}, &model.Category{}, &model.User{})
+}
+
+// InitExchange - starts orderbooks of every trading market with their open
+// orders, models have to be set up first
+func (s *Server) InitExchange() {
+	s.Exchange = exchange.NewRegistry()
+	s.Exchange.Placed = s.placed
+	if err := s.Exchange.Load(s.DB); err != nil {
+		s.Log.Fatal("Failed to load markets")
+	}
 }
 
 func (s *Server) ConnectCache() {
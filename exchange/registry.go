@@ -0,0 +1,366 @@
+package exchange
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/timadinorth/bet-exchange/model"
+	"github.com/timadinorth/bet-exchange/orderbook"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMarketNotFound = errors.New("exchange: market not found")
+	ErrRunnerNotFound = errors.New("exchange: runner not found")
+	ErrMarketExists   = errors.New("exchange: market already exists")
+	ErrNoRunners      = errors.New("exchange: market has no runners")
+)
+
+// Market - in-memory orderbooks of persisted market, one selection per
+// runner. Orderbook market is not safe for concurrent use, so it is changed
+// only through Submit and read inside Sequencer.View
+type Market struct {
+	ID        uint
+	Book      *orderbook.Market
+	Sequencer *orderbook.Sequencer
+
+	mu      sync.Mutex
+	handle  PlacedHandler
+	results chan orderbook.Result // published by the sequencer, nil without handler
+	notify  chan struct{}         // released results were queued
+
+	qmu    sync.Mutex // guards fields below
+	queued *sync.Cond
+	queue  []orderbook.Result // released results waiting to be handled
+	seen   uint64             // last result taken from results
+	closed bool
+}
+
+// Exclusive - runs fn while no other Exclusive call of the market runs, so
+// that commands submitted by fn are persisted in the order they were applied
+func (m *Market) Exclusive(fn func() error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return fn()
+}
+
+// Submit - submits command to the sequencer, has to run inside Exclusive.
+// Orders released before the command are handled first, so that results
+// are persisted in the order they were applied
+func (m *Market) Submit(cmd orderbook.Command) orderbook.Result {
+	res := m.Sequencer.Submit(cmd)
+	m.flush(res.Seq)
+	return res
+}
+
+// Selection - id of the runner's selection in the orderbook market
+func Selection(runnerID uint) string {
+	return strconv.FormatUint(uint64(runnerID), 10)
+}
+
+// Owner - owner of the user's orders in the orderbook
+func Owner(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// PlacedHandler - persists result of the order placed outside of a request:
+// released after bet delay or placed again on startup. Results of a market
+// are handled inside Exclusive in the order they were applied
+type PlacedHandler func(m *Market, res orderbook.Result)
+
+// consume - takes results published by the sequencer. Released ones are
+// queued without limit and handled inside Exclusive, either by the next
+// Submit or in the background. The sequencer is never blocked, as holder of
+// Exclusive may wait for it. Stops once results are closed
+func (m *Market) consume() {
+	m.notify = make(chan struct{}, 1)
+	m.queued = sync.NewCond(&m.qmu)
+
+	go func() {
+		for res := range m.results {
+			m.qmu.Lock()
+			if res.Released {
+				m.queue = append(m.queue, res)
+			}
+			m.seen = res.Seq
+			m.queued.Broadcast()
+			m.qmu.Unlock()
+
+			if res.Released {
+				select {
+				case m.notify <- struct{}{}:
+				default:
+				}
+			}
+		}
+
+		m.qmu.Lock()
+		m.closed = true
+		m.queued.Broadcast()
+		m.qmu.Unlock()
+		close(m.notify)
+	}()
+
+	go func() {
+		for range m.notify {
+			_ = m.Exclusive(func() error {
+				m.flush(0)
+				return nil
+			})
+		}
+	}()
+}
+
+// flush - handles released results published before result seq, once the
+// consumer has taken it. Zero seq handles every queued result. Has to run
+// inside Exclusive
+func (m *Market) flush(seq uint64) {
+	if m.results == nil {
+		return
+	}
+
+	m.qmu.Lock()
+	for seq > 0 && m.seen < seq && !m.closed {
+		m.queued.Wait()
+	}
+	n := len(m.queue)
+	if seq > 0 {
+		n = sort.Search(n, func(i int) bool { return m.queue[i].Seq > seq })
+	}
+	released := m.queue[:n:n]
+	m.queue = m.queue[n:]
+	m.qmu.Unlock()
+
+	for _, res := range released {
+		m.handle(m, res)
+	}
+}
+
+// stop - stops the sequencer, results already published are still handled
+func (m *Market) stop() {
+	m.Sequencer.Stop()
+	if m.results != nil {
+		close(m.results)
+	}
+}
+
+// bookOrder - orderbook order of the persisted open order with its
+// unmatched stake
+func bookOrder(order *model.Order) (*orderbook.Order, error) {
+	side := orderbook.Back
+	if order.Side == model.Lay {
+		side = orderbook.Lay
+	}
+	o, err := orderbook.NewOrder(side, order.Price, order.Remaining)
+	if err != nil {
+		return nil, err
+	}
+	o.Id = order.BookId
+	o.Owner = Owner(order.UserID)
+	return o, nil
+}
+
+// Registry - running orderbook markets of persisted markets with lookup by
+// market and runner id. Placed is optional and has to be set before markets
+// are added
+type Registry struct {
+	Placed PlacedHandler
+
+	mu      sync.RWMutex
+	markets map[uint]*Market
+	runners map[uint]uint // market id by runner id
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		markets: make(map[uint]*Market),
+		runners: make(map[uint]uint),
+	}
+}
+
+// Load - adds every open and suspended market of the database together with
+// its open orders, used on startup
+func (r *Registry) Load(DB *gorm.DB) error {
+	markets, err := model.FindTradingMarkets(DB)
+	if err != nil {
+		return err
+	}
+	for i := range markets {
+		orders, err := model.FindOpenOrders(DB, markets[i].ID)
+		if err != nil {
+			return err
+		}
+		if _, err := r.Add(&markets[i], orders...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add - creates orderbook market of the persisted market with its runners
+// and starts its sequencer. Open orders of the market, if any, are placed
+// again in the order of their ids. Resting ones did not cross, so they rest
+// as they did before. Pending ones are placed as new orders, held by bet
+// delay again or matched at once, their results are passed to Placed
+func (r *Registry) Add(m *model.Market, orders ...model.Order) (*Market, error) {
+	if len(m.Runners) == 0 {
+		return nil, ErrNoRunners
+	}
+
+	selections := make([]string, 0, len(m.Runners))
+	for _, runner := range m.Runners {
+		selections = append(selections, Selection(runner.ID))
+	}
+	book, err := orderbook.NewMarket(strconv.FormatUint(uint64(m.ID), 10), selections...)
+	if err != nil {
+		return nil, err
+	}
+	// restored orders already lived through the off, so they must not lapse
+	if m.InPlay {
+		if _, _, err := book.TurnInPlay(); err != nil {
+			return nil, err
+		}
+	}
+
+	// bet delay is set afterwards, as resting orders are not held again
+	var pending []orderbook.Command
+	for i := range orders {
+		o, err := bookOrder(&orders[i])
+		if err != nil {
+			return nil, err
+		}
+		cmd := orderbook.Command{Type: orderbook.PlaceCommand, Selection: Selection(orders[i].RunnerID), Order: o}
+		if orders[i].Status == model.OrderPending {
+			pending = append(pending, cmd)
+		} else if _, _, err := book.AddOrder(cmd.Selection, o); err != nil {
+			return nil, err
+		}
+	}
+
+	book.BetDelay = time.Duration(m.BetDelay) * time.Second
+	if m.Status == model.MarketSuspended {
+		if err := book.Suspend(); err != nil {
+			return nil, err
+		}
+	}
+
+	// sequencer is not started yet, so book is still safe to use
+	var placed []orderbook.Result
+	for _, cmd := range pending {
+		res := orderbook.Result{Command: cmd}
+		res.Trades, _, res.Err = book.AddOrder(cmd.Selection, cmd.Order)
+		if res.Err == nil && cmd.Order.Status == orderbook.Pending {
+			continue
+		}
+		if res.Err != nil {
+			cmd.Order.Status = orderbook.Cancelled
+		}
+		o := *cmd.Order
+		res.Order = &o
+		res.Command.Order = &o
+		placed = append(placed, res)
+	}
+
+	market, err := r.add(m, book)
+	if err != nil {
+		return nil, err
+	}
+	if r.Placed != nil {
+		_ = market.Exclusive(func() error {
+			for _, res := range placed {
+				r.Placed(market, res)
+			}
+			return nil
+		})
+	}
+	return market, nil
+}
+
+// add - starts sequencer of the book and registers its market
+func (r *Registry) add(m *model.Market, book *orderbook.Market) (*Market, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.markets[m.ID]; ok {
+		return nil, ErrMarketExists
+	}
+	market := &Market{ID: m.ID, Book: book, handle: r.Placed}
+	if market.handle != nil {
+		market.results = make(chan orderbook.Result)
+	}
+	market.Sequencer = orderbook.NewSequencer(book, market.results)
+	if err := market.Sequencer.Start(); err != nil {
+		return nil, err
+	}
+	if market.handle != nil {
+		market.consume()
+	}
+	r.markets[m.ID] = market
+	for _, runner := range m.Runners {
+		r.runners[runner.ID] = m.ID
+	}
+	return market, nil
+}
+
+// Market - returns running market by id
+func (r *Registry) Market(id uint) (*Market, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.markets[id]
+	if !ok {
+		return nil, ErrMarketNotFound
+	}
+	return m, nil
+}
+
+// Book - returns orderbook of the runner together with its market
+func (r *Registry) Book(runnerID uint) (*Market, *orderbook.Orderbook, error) {
+	r.mu.RLock()
+	m, ok := r.markets[r.runners[runnerID]]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, nil, ErrRunnerNotFound
+	}
+
+	ob, err := m.Book.Book(Selection(runnerID))
+	if err != nil {
+		return nil, nil, ErrRunnerNotFound
+	}
+	return m, ob, nil
+}
+
+// Remove - stops and forgets the market, e.g. once it is closed
+func (r *Registry) Remove(id uint) {
+	r.mu.Lock()
+	m, ok := r.markets[id]
+	if ok {
+		delete(r.markets, id)
+		for runner, market := range r.runners {
+			if market == id {
+				delete(r.runners, runner)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	if ok {
+		m.stop()
+	}
+}
+
+// Close - stops every market
+func (r *Registry) Close() {
+	r.mu.Lock()
+	markets := r.markets
+	r.markets = make(map[uint]*Market)
+	r.runners = make(map[uint]uint)
+	r.mu.Unlock()
+
+	for _, m := range markets {
+		m.stop()
+	}
+}
@@ -0,0 +1,221 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/timadinorth/bet-exchange/model"
+	"github.com/timadinorth/bet-exchange/orderbook"
+)
+
+func newTestMarket(id uint, runners ...uint) *model.Market {
+	m := &model.Market{Type: model.MatchOdds, Name: "Match Odds", Status: model.MarketOpen}
+	m.ID = id
+	for _, r := range runners {
+		runner := model.Runner{MarketID: id}
+		runner.ID = r
+		m.Runners = append(m.Runners, runner)
+	}
+	return m
+}
+
+func TestRegistryAdd(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+
+	m, err := r.Add(newTestMarket(1, 10, 11, 12))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10", "11", "12"}, m.Book.Selections())
+	assert.Equal(t, orderbook.Open, m.Book.Status())
+
+	found, err := r.Market(1)
+	assert.Nil(t, err)
+	assert.Equal(t, m, found)
+
+	market, ob, err := r.Book(11)
+	assert.Nil(t, err)
+	assert.Equal(t, m, market)
+	expected, _ := m.Book.Book("11")
+	assert.Equal(t, expected, ob)
+
+	_, err = r.Add(newTestMarket(1, 13))
+	assert.Equal(t, ErrMarketExists, err)
+	_, err = r.Add(newTestMarket(2))
+	assert.Equal(t, ErrNoRunners, err)
+	_, err = r.Market(2)
+	assert.Equal(t, ErrMarketNotFound, err)
+	_, _, err = r.Book(13)
+	assert.Equal(t, ErrRunnerNotFound, err)
+}
+
+func TestRegistryMarketState(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+
+	in := newTestMarket(1, 10, 11)
+	in.InPlay = true
+	in.BetDelay = 5
+	in.Status = model.MarketSuspended
+	m, err := r.Add(in)
+	assert.Nil(t, err)
+	assert.Equal(t, orderbook.Suspended, m.Book.Status())
+	assert.Equal(t, "5s", m.Book.BetDelay.String())
+
+	assert.Nil(t, m.Book.Reopen())
+	assert.Equal(t, orderbook.InPlay, m.Book.Status())
+}
+
+func TestRegistrySequencer(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+
+	m, _ := r.Add(newTestMarket(1, 10, 11))
+	o, _ := orderbook.NewOrder(orderbook.Back, decimal.NewFromFloat(2.5), decimal.NewFromInt(10))
+	res := m.Sequencer.Submit(orderbook.Command{Type: orderbook.PlaceCommand, Selection: Selection(10), Order: o})
+	assert.Nil(t, res.Err)
+
+	var best orderbook.Price
+	assert.Nil(t, m.Sequencer.View(func(*orderbook.Market) {
+		_, ob, _ := r.Book(10)
+		best = ob.BestBack()
+	}))
+	assert.Equal(t, o.Price, best)
+
+	r.Remove(1)
+	_, err := r.Market(1)
+	assert.Equal(t, ErrMarketNotFound, err)
+	_, _, err = r.Book(10)
+	assert.Equal(t, ErrRunnerNotFound, err)
+	assert.Equal(t, orderbook.ErrSequencerStopped, m.Sequencer.Submit(orderbook.Command{Type: orderbook.SuspendCommand}).Err)
+}
+
+func newTestOrder(bookId string, userID, runnerID uint, side model.OrderSide, price float64, stake int64, status model.OrderStatus) model.Order {
+	return model.Order{UserID: userID, RunnerID: runnerID, BookId: bookId, Side: side,
+		Price: decimal.NewFromFloat(price), Remaining: decimal.NewFromInt(stake), Status: status}
+}
+
+func TestRegistryOpenOrders(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	var placed []orderbook.Result
+	r.Placed = func(m *Market, res orderbook.Result) { placed = append(placed, res) }
+
+	m, err := r.Add(newTestMarket(1, 10, 11),
+		newTestOrder("a", 1, 10, model.Lay, 2.5, 10, model.OrderResting),
+		newTestOrder("b", 1, 10, model.Lay, 2.5, 5, model.OrderResting),
+		newTestOrder("c", 2, 10, model.Back, 2.5, 4, model.OrderPending))
+	assert.Nil(t, err)
+
+	// pending order is matched with the first resting one
+	if assert.Len(t, placed, 1) {
+		assert.Equal(t, "c", placed[0].Order.Id)
+		assert.Equal(t, orderbook.Filled, placed[0].Order.Status)
+		if assert.Len(t, placed[0].Trades, 1) {
+			assert.Equal(t, "a", placed[0].Trades[0].MakerId)
+		}
+	}
+
+	var depth orderbook.Depth
+	assert.Nil(t, m.Sequencer.View(func(book *orderbook.Market) { depth, _ = book.Depth(Selection(10), 0) }))
+	if assert.Len(t, depth.Lay, 1) {
+		assert.Equal(t, orderbook.Amount(1100), depth.Lay[0].Volume)
+		assert.Equal(t, 2, depth.Lay[0].Orders)
+	}
+
+	suspended := newTestMarket(2, 20, 21)
+	suspended.Status = model.MarketSuspended
+	_, err = r.Add(suspended, newTestOrder("d", 1, 20, model.Back, 2.0, 5, model.OrderPending))
+	assert.Nil(t, err)
+	if assert.Len(t, placed, 2) {
+		assert.Equal(t, orderbook.ErrMarketSuspended, placed[1].Err)
+		assert.Equal(t, orderbook.Cancelled, placed[1].Order.Status)
+	}
+}
+
+func TestRegistryReleased(t *testing.T) {
+	r := NewRegistry()
+	placed := make(chan orderbook.Result, 1)
+	r.Placed = func(m *Market, res orderbook.Result) { placed <- res }
+
+	in := newTestMarket(1, 10, 11)
+	in.InPlay = true
+	in.BetDelay = 1
+	m, _ := r.Add(in)
+	o, _ := orderbook.NewOrder(orderbook.Back, decimal.NewFromFloat(2.5), decimal.NewFromInt(10))
+	res := m.Sequencer.Submit(orderbook.Command{Type: orderbook.PlaceCommand, Selection: Selection(10), Order: o})
+	assert.Nil(t, res.Err)
+
+	// submitted result is left to the submitter
+	select {
+	case res = <-placed:
+		assert.True(t, res.Released)
+		assert.Equal(t, o.Id, res.Order.Id)
+		assert.Equal(t, orderbook.Resting, res.Order.Status)
+	case <-time.After(3 * time.Second):
+		t.Fatal("released order was not handled")
+	}
+
+	r.Close()
+	assert.Empty(t, placed)
+}
+
+func TestRegistryOpenOrdersInPlay(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	var placed []orderbook.Result
+	r.Placed = func(m *Market, res orderbook.Result) { placed = append(placed, res) }
+
+	in := newTestMarket(1, 10, 11)
+	in.InPlay = true
+	in.BetDelay = 5
+	m, err := r.Add(in,
+		newTestOrder("a", 1, 10, model.Lay, 2.5, 10, model.OrderResting),
+		newTestOrder("b", 2, 10, model.Back, 2.5, 4, model.OrderPending))
+	assert.Nil(t, err)
+	assert.Equal(t, orderbook.InPlay, m.Book.Status())
+
+	// resting order neither lapses nor waits for bet delay, pending one is
+	// held again
+	assert.Empty(t, placed)
+	var depth orderbook.Depth
+	assert.Nil(t, m.Sequencer.View(func(book *orderbook.Market) { depth, _ = book.Depth(Selection(10), 0) }))
+	if assert.Len(t, depth.Lay, 1) {
+		assert.Equal(t, orderbook.Amount(1000), depth.Lay[0].Volume)
+	}
+	res := m.Sequencer.Submit(orderbook.Command{Type: orderbook.CancelCommand, OrderId: "a"})
+	assert.Nil(t, res.Err)
+	res = m.Sequencer.Submit(orderbook.Command{Type: orderbook.CancelCommand, OrderId: "b"})
+	assert.Equal(t, orderbook.ErrOrderNotFound, res.Err)
+}
+
+func TestRegistrySubmitHandlesReleasedFirst(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	var handled []orderbook.Result
+	r.Placed = func(m *Market, res orderbook.Result) { handled = append(handled, res) }
+
+	in := newTestMarket(1, 10, 11)
+	in.InPlay = true
+	in.BetDelay = 1
+	m, _ := r.Add(in)
+	o, _ := orderbook.NewOrder(orderbook.Back, decimal.NewFromFloat(2.5), decimal.NewFromInt(10))
+
+	assert.Nil(t, m.Exclusive(func() error {
+		res := m.Submit(orderbook.Command{Type: orderbook.PlaceCommand, Selection: Selection(10), Order: o})
+		assert.Nil(t, res.Err)
+		assert.Equal(t, orderbook.Pending, res.Order.Status)
+
+		// order is released while Exclusive is held, so only Submit of the
+		// cancel can handle it
+		time.Sleep(1500 * time.Millisecond)
+		res = m.Submit(orderbook.Command{Type: orderbook.CancelCommand, OrderId: o.Id})
+		assert.Nil(t, res.Err)
+		if assert.Len(t, handled, 1) {
+			assert.Equal(t, orderbook.Resting, handled[0].Order.Status)
+			assert.Less(t, handled[0].Seq, res.Seq)
+		}
+		return nil
+	}))
+}
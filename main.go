@@ -22,6 +22,7 @@ func main() {
 	if err != nil {
 		s.Log.Fatal("Failed to run db migrations")
 	}
+	s.InitExchange()
 	s.Log.Info("starting...")
 	s.Start()
 }
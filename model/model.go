@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -67,13 +68,277 @@ type Category struct {
 
 type Competition struct {
 	Default
-	Name       string `gorm:"not null" json:"name"`
-	Type       string `json:"type"`
-	EventCount int    `gorm:"default: 0" json:"event_count"`
+	CategoryID uint      `gorm:"not null;index" json:"category_id"`
+	Category   *Category `json:"category,omitempty"`
+	Name       string    `gorm:"not null" json:"name" example:"Premier League"`
+	Type       string    `json:"type" example:"league"`
+	EventCount int       `gorm:"default: 0" json:"event_count"`
+}
+
+// countEvents - recounts events of the competitions, deleted ones excluded.
+// Every competition is recounted when any id is unknown, e.g. after batch
+// update or delete of events
+func countEvents(tx *gorm.DB, competitionIDs ...uint) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	for _, id := range competitionIDs {
+		if id == 0 {
+			return db.Exec(`UPDATE competitions SET event_count = (SELECT count(*) FROM events
+				WHERE events.competition_id = competitions.id AND events.deleted_at IS NULL)`).Error
+		}
+	}
+
+	for _, id := range competitionIDs {
+		count := db.Model(&Event{}).Select("count(*)").Where("competition_id = ?", id)
+		if err := db.Model(&Competition{}).Where("id = ?", id).UpdateColumn("event_count", count).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type EventStatus string
+
+const (
+	EventScheduled EventStatus = "scheduled"
+	EventInPlay    EventStatus = "in_play"
+	EventFinished  EventStatus = "finished"
+	EventAbandoned EventStatus = "abandoned"
+)
+
+func (status EventStatus) Valid() bool {
+	switch status {
+	case EventScheduled, EventInPlay, EventFinished, EventAbandoned:
+		return true
+	}
+	return false
 }
 
 type Event struct {
+	Default
+	CompetitionID  uint          `gorm:"not null;index" json:"competition_id"`
+	Competition    *Competition  `json:"competition,omitempty"`
+	Name           string        `gorm:"not null" json:"name" example:"Arsenal v Chelsea"`
+	ScheduledStart time.Time     `gorm:"not null;index" json:"scheduled_start"`
+	Status         EventStatus   `gorm:"not null;default:scheduled;index" json:"status" example:"scheduled"`
+	Venue          string        `json:"venue" example:"Emirates Stadium"`
+	ExternalId     string        `gorm:"index" json:"external_id"`
+	Participants   []Participant `gorm:"constraint:OnDelete:CASCADE" json:"participants,omitempty"`
+	Outcomes       []Outcome     `gorm:"constraint:OnDelete:CASCADE" json:"outcomes,omitempty"`
+}
+
+// AfterCreate - keeps EventCount of the competition accurate
+func (event *Event) AfterCreate(tx *gorm.DB) error {
+	return countEvents(tx, event.CompetitionID)
+}
+
+// BeforeUpdate - remembers competition of the event, which may be changed
+func (event *Event) BeforeUpdate(tx *gorm.DB) error {
+	if event.ID == 0 {
+		return nil
+	}
+	var previous uint
+	err := tx.Session(&gorm.Session{NewDB: true}).Model(&Event{}).Select("competition_id").
+		Where("id = ?", event.ID).Scan(&previous).Error
+	tx.InstanceSet("event:competition_id", previous)
+	return err
+}
+
+// AfterUpdate - keeps EventCount of both the previous and the current
+// competition accurate
+func (event *Event) AfterUpdate(tx *gorm.DB) error {
+	previous, _ := tx.InstanceGet("event:competition_id")
+	id, _ := previous.(uint)
+	if event.ID == 0 || id == event.CompetitionID {
+		return countEvents(tx, event.CompetitionID)
+	}
+	return countEvents(tx, id, event.CompetitionID)
+}
+
+// AfterDelete - keeps EventCount of the competition accurate
+func (event *Event) AfterDelete(tx *gorm.DB) error {
+	return countEvents(tx, event.CompetitionID)
+}
+
+// FindById - loads event together with its participants and outcomes, both in sort order
+func (event *Event) FindById(DB *gorm.DB, id uint) error {
+	return DB.Model(Event{}).
+		Preload("Participants", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).
+		Preload("Outcomes", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).
+		Take(event, id).Error
+}
+
+type Participant struct {
+	Default
+	EventID    uint   `gorm:"not null;index" json:"-"`
+	Name       string `gorm:"not null" json:"name" example:"Arsenal"`
+	Role       string `json:"role" example:"home"`
+	SortOrder  int    `gorm:"not null;default:0" json:"sort_order"`
+	ExternalId string `json:"external_id"`
 }
 
 type Outcome struct {
+	Default
+	EventID    uint            `gorm:"not null;index" json:"event_id"`
+	Name       string          `gorm:"not null" json:"name" example:"Draw"`
+	SortOrder  int             `gorm:"not null;default:0" json:"sort_order"`
+	Handicap   decimal.Decimal `gorm:"type:numeric(8,2);not null;default:0" json:"handicap" example:"-1.5"`
+	ExternalId string          `json:"external_id"`
+}
+
+type MarketType string
+
+const (
+	MatchOdds MarketType = "match_odds"
+	OverUnder MarketType = "over_under"
+	Handicap  MarketType = "handicap"
+)
+
+func (t MarketType) Valid() bool {
+	switch t {
+	case MatchOdds, OverUnder, Handicap:
+		return true
+	}
+	return false
+}
+
+type MarketStatus string
+
+const (
+	MarketOpen      MarketStatus = "open"
+	MarketSuspended MarketStatus = "suspended"
+	MarketClosed    MarketStatus = "closed"
+	MarketSettled   MarketStatus = "settled"
+)
+
+func (status MarketStatus) Valid() bool {
+	switch status {
+	case MarketOpen, MarketSuspended, MarketClosed, MarketSettled:
+		return true
+	}
+	return false
+}
+
+// Trading - reports whether market has to be kept in the exchange
+func (status MarketStatus) Trading() bool {
+	return status == MarketOpen || status == MarketSuspended
+}
+
+type Market struct {
+	Default
+	EventID        uint            `gorm:"not null;index" json:"event_id"`
+	Event          *Event          `json:"event,omitempty"`
+	Type           MarketType      `gorm:"not null" json:"type" example:"over_under"`
+	Name           string          `gorm:"not null" json:"name" example:"Over/Under 2.5 Goals"`
+	Line           decimal.Decimal `gorm:"type:numeric(8,2);not null;default:0" json:"line" example:"2.5"`
+	Status         MarketStatus    `gorm:"not null;default:open;index" json:"status" example:"open"`
+	InPlay         bool            `gorm:"not null;default:false" json:"in_play"`
+	BetDelay       uint            `gorm:"not null;default:0" json:"bet_delay" example:"5"` // seconds in-play orders are held before matching
+	CommissionRate decimal.Decimal `gorm:"type:numeric(5,4);not null;default:0" json:"commission_rate" example:"0.05"`
+	Runners        []Runner        `gorm:"constraint:OnDelete:CASCADE" json:"runners,omitempty"`
+}
+
+// FindById - loads market together with its runners in sort order
+func (market *Market) FindById(DB *gorm.DB, id uint) error {
+	return DB.Model(Market{}).
+		Preload("Runners", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).
+		Take(market, id).Error
+}
+
+// FindEventMarkets - loads markets of the event with their runners
+func FindEventMarkets(DB *gorm.DB, eventID uint) ([]Market, error) {
+	var markets []Market
+	err := DB.Model(Market{}).
+		Preload("Runners", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).
+		Where("event_id = ?", eventID).
+		Order("id").
+		Find(&markets).Error
+	return markets, err
+}
+
+// FindTradingMarkets - loads open and suspended markets with their runners
+func FindTradingMarkets(DB *gorm.DB) ([]Market, error) {
+	var markets []Market
+	err := DB.Model(Market{}).
+		Preload("Runners", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).
+		Where("status IN ?", []MarketStatus{MarketOpen, MarketSuspended}).
+		Order("id").
+		Find(&markets).Error
+	return markets, err
+}
+
+// Runner - selection of the market, usually one of the event outcomes
+type Runner struct {
+	Default
+	MarketID  uint     `gorm:"not null;index" json:"market_id"`
+	OutcomeID *uint    `gorm:"index" json:"outcome_id,omitempty"`
+	Outcome   *Outcome `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+	Name      string   `gorm:"not null" json:"name" example:"Over 2.5"`
+	SortOrder int      `gorm:"not null;default:0" json:"sort_order"`
+}
+
+type OrderSide string
+
+const (
+	Back OrderSide = "back"
+	Lay  OrderSide = "lay"
+)
+
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending" // held by bet delay
+	OrderResting   OrderStatus = "resting"
+	OrderFilled    OrderStatus = "filled"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderKilled    OrderStatus = "killed"
+)
+
+// Open - reports whether order still has stake waiting to be matched
+func (status OrderStatus) Open() bool {
+	return status == OrderPending || status == OrderResting
+}
+
+// Order - order of the user placed in the orderbook of the runner. Remaining
+// is the unmatched stake, Matched sums its fills
+type Order struct {
+	Default
+	UserID    uint            `gorm:"not null;index" json:"-"`
+	User      *User           `json:"-"`
+	MarketID  uint            `gorm:"not null;index" json:"market_id"`
+	Market    *Market         `json:"-"`
+	RunnerID  uint            `gorm:"not null;index" json:"runner_id"`
+	Runner    *Runner         `json:"-"`
+	BookId    string          `gorm:"not null;uniqueIndex" json:"-"` // id of the order in the orderbook
+	Side      OrderSide       `gorm:"not null" json:"side" example:"back"`
+	Price     decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"price" example:"2.5"`
+	Stake     decimal.Decimal `gorm:"type:numeric(16,2);not null" json:"stake" example:"10"`
+	Matched   decimal.Decimal `gorm:"type:numeric(16,2);not null;default:0" json:"matched" example:"4"`
+	Remaining decimal.Decimal `gorm:"type:numeric(16,2);not null;default:0" json:"remaining" example:"6"`
+	Status    OrderStatus     `gorm:"not null;index" json:"status" example:"resting"`
+	Fills     []Fill          `gorm:"constraint:OnDelete:CASCADE" json:"fills"`
+}
+
+// Fill - matched part of the order, Seq is the trade sequence of the market
+type Fill struct {
+	Default
+	OrderID uint            `gorm:"not null;index" json:"-"`
+	Seq     uint64          `gorm:"not null" json:"seq"`
+	Price   decimal.Decimal `gorm:"type:numeric(8,2);not null" json:"price" example:"2.5"`
+	Stake   decimal.Decimal `gorm:"type:numeric(16,2);not null" json:"stake" example:"4"`
+}
+
+// FindByBookId - loads order by its id in the orderbook
+func (order *Order) FindByBookId(DB *gorm.DB, bookId string) error {
+	return DB.Model(Order{}).Where("book_id = ?", bookId).Take(order).Error
+}
+
+// FindOpenOrders - loads pending and resting orders of the market in the
+// order they were placed
+func FindOpenOrders(DB *gorm.DB, marketID uint) ([]Order, error) {
+	var orders []Order
+	err := DB.Model(Order{}).
+		Where("market_id = ? AND status IN ?", marketID, []OrderStatus{OrderPending, OrderResting}).
+		Order("id").
+		Find(&orders).Error
+	return orders, err
 }
@@ -0,0 +1,140 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	benchLevels = 100 // price levels on each side of the book
+	benchDepth  = 10  // resting orders on every level
+)
+
+// benchPrices - consecutive ladder prices starting at from
+func benchPrices(b *testing.B, from float64, n int) []decimal.Decimal {
+	b.Helper()
+
+	prices := make([]decimal.Decimal, n)
+	prices[0] = decimal.NewFromFloat(from)
+	for i := 1; i < n; i++ {
+		p, err := DefaultLadder.NextTickUp(prices[i-1])
+		if err != nil {
+			b.Fatal(err)
+		}
+		prices[i] = p
+	}
+	return prices
+}
+
+// benchOrders - orders alternating over the prices
+func benchOrders(b *testing.B, side Side, prices []decimal.Decimal, n int) []*Order {
+	b.Helper()
+
+	stake := decimal.NewFromInt(10)
+	orders := make([]*Order, n)
+	for i := range orders {
+		o, err := NewOrder(side, prices[i%len(prices)], stake)
+		if err != nil {
+			b.Fatal(err)
+		}
+		orders[i] = o
+	}
+	return orders
+}
+
+// newBenchBook - book of realistic size with lays below 2.0 and backs above
+func newBenchBook(b *testing.B) (ob *Orderbook, lays, backs []decimal.Decimal) {
+	b.Helper()
+
+	lays = benchPrices(b, 1.5, benchLevels)
+	backs = benchPrices(b, 2.5, benchLevels)
+	ob = NewOrderbook()
+	for _, o := range append(benchOrders(b, Lay, lays, benchLevels*benchDepth), benchOrders(b, Back, backs, benchLevels*benchDepth)...) {
+		if _, _, err := ob.AddOrder(o); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return
+}
+
+func BenchmarkOrderbookAddOrder(b *testing.B) {
+	ob, _, backs := newBenchBook(b)
+	orders := benchOrders(b, Back, backs, b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for _, o := range orders {
+		ob.AddOrder(o)
+	}
+}
+
+func BenchmarkOrderbookMatch(b *testing.B) {
+	ob, lays, _ := newBenchBook(b)
+	best := lays[len(lays)-1:]
+	makers := benchOrders(b, Lay, best, b.N)
+	takers := benchOrders(b, Back, best, b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range makers {
+		ob.AddOrder(makers[i])
+		ob.AddOrder(takers[i])
+	}
+}
+
+func BenchmarkOrderbookCancelOrder(b *testing.B) {
+	ob, _, backs := newBenchBook(b)
+	orders := benchOrders(b, Back, backs, b.N)
+	for _, o := range orders {
+		ob.AddOrder(o)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for _, o := range orders {
+		ob.CancelOrder(o.Id)
+	}
+}
+
+func BenchmarkOrderbookDepth(b *testing.B) {
+	ob, _, _ := newBenchBook(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.Depth(10)
+	}
+}
+
+func BenchmarkOrderbookBest(b *testing.B) {
+	ob, _, _ := newBenchBook(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.BestBack()
+		ob.BestLay()
+	}
+}
+
+func BenchmarkMarketAddOrder(b *testing.B) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	for _, s := range m.Selections() {
+		ob, _, _ := newBenchBook(b)
+		book := m.books[s]
+		for _, side := range []Side{Back, Lay} {
+			for _, o := range ob.Orders(side) {
+				o.Status = Pending
+				book.AddOrder(o)
+			}
+		}
+	}
+	orders := benchOrders(b, Back, benchPrices(b, 2.5, benchLevels), b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for _, o := range orders {
+		m.AddOrder("home", o)
+	}
+}
@@ -0,0 +1,131 @@
+package orderbook
+
+// stakePlaces - precision of stakes generated by cross-matching, a minor unit
+const stakePlaces = -amountExp
+
+// virtualLiquidity - source of liquidity generated outside of the book
+type virtualLiquidity interface {
+	// best - returns virtual level available to the taker of the side
+	best(side Side) (price Price, volume Amount, ok bool)
+	// fill - matches stake of the order at virtual price
+	fill(o *Order, price Price, stake Amount) []Trade
+}
+
+// crossMatcher - generates virtual liquidity for a selection from the books
+// of all other selections of the market. Backing a selection is equivalent to
+// laying all the others, so resting backs on the other selections at prices
+// q1..qn form a virtual lay at 1 / (1 - 1/q1 - ... - 1/qn), rounded to the
+// ladder in favour of the makers. The same holds for lays. Self-trade
+// prevention does not apply to virtual liquidity
+type crossMatcher struct {
+	market    *Market
+	selection string
+}
+
+// impliedScale - fixed point of implied probabilities. Summing them is
+// inexact, so implied price within snapTolerance of a hundredth is taken as
+// that hundredth, e.g. two legs at 3.0 give 3.0 rather than just above it
+const (
+	impliedScale  = 1_000_000_000_000
+	snapTolerance = 1000 // inverse, a thousandth of a hundredth
+)
+
+// best - virtual level from implied probabilities of the legs, put on the
+// ladder in favour of the makers: down for backs and up for lays. Volume is
+// limited by the leg paying out the least
+func (cm *crossMatcher) best(side Side) (price Price, volume Amount, ok bool) {
+	var sum, payout int64
+	legs := 0
+	for _, s := range cm.market.selections {
+		if s == cm.selection {
+			continue
+		}
+		// every other selection has to have liquidity
+		l := cm.market.books[s].sideLevels(side).best()
+		if l == nil {
+			return
+		}
+
+		p := int64(l.Price)
+		sum += (impliedScale*int64(priceOne) + p/2) / p
+		// minor units times hundredths
+		if v := int64(l.TotalVolume) * p; legs == 0 || v < payout {
+			payout = v
+		}
+		legs++
+	}
+	if legs == 0 || sum >= impliedScale {
+		return
+	}
+
+	num, den := impliedScale*int64(priceOne), impliedScale-sum
+	odds, rem := num/den, num%den
+	switch {
+	case rem*snapTolerance < den:
+	case (den-rem)*snapTolerance < den, side == Lay:
+		odds++
+	}
+	if side == Back {
+		price, ok = DefaultLadder.floor(Price(odds))
+	} else {
+		price, ok = DefaultLadder.ceil(Price(odds))
+	}
+	if !ok {
+		return 0, 0, false
+	}
+
+	volume = Amount(payout / int64(price))
+	if volume <= 0 {
+		return 0, 0, false
+	}
+	return price, volume, true
+}
+
+func (cm *crossMatcher) fill(o *Order, price Price, stake Amount) []Trade {
+	ob := cm.market.books[cm.selection]
+	*ob.seq++
+	trades := []Trade{{
+		Seq:       *ob.seq,
+		Selection: cm.selection,
+		TakerId:   o.Id,
+		Side:      o.Side,
+		Price:     price,
+		Stake:     stake,
+	}}
+
+	// payout in minor units times hundredths, so that stake of every leg is
+	// rounded to a minor unit only once
+	payout := int64(stake) * int64(price)
+	for _, s := range cm.market.selections {
+		if s == cm.selection {
+			continue
+		}
+		leg := cm.market.books[s]
+		l := leg.sideLevels(o.Side).best()
+		c := minAmount(Amount((payout+int64(l.Price)/2)/int64(l.Price)), l.TotalVolume)
+		if c <= 0 {
+			continue
+		}
+		t, _, _ := leg.match(l, o.Id, "", o.Side.Opposite(), c)
+		trades = append(trades, t...)
+	}
+	return trades
+}
+
+// mergeLevel - adds virtual level to levels sorted from the best price for
+// the taker of the side
+func mergeLevel(levels []Level, v Level, side Side) []Level {
+	for i, l := range levels {
+		if l.Price == v.Price {
+			levels[i].Volume = l.Volume + v.Volume
+			return levels
+		}
+		if side.better(v.Price, l.Price) {
+			levels = append(levels, Level{})
+			copy(levels[i+1:], levels[i:])
+			levels[i] = v
+			return levels
+		}
+	}
+	return append(levels, v)
+}
@@ -0,0 +1,144 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestMarket - helper to create match odds market with resting orders
+func newTestMarket(t *testing.T, orders map[string][]*Order) *Market {
+	t.Helper()
+
+	m, err := NewMarket("1", "home", "draw", "away")
+	if err != nil {
+		t.Fatalf("Error creating test market: %s", err)
+	}
+	for _, s := range m.Selections() {
+		for _, o := range orders[s] {
+			if _, _, err := m.AddOrder(s, o); err != nil {
+				t.Fatalf("Error adding test order: %s", err)
+			}
+		}
+	}
+	return m
+}
+
+func TestCrossMatchDepth(t *testing.T) {
+	m := newTestMarket(t, map[string][]*Order{
+		"home": {newTestOrder(t, Lay, 1.9, 10.0)},
+		"draw": {newTestOrder(t, Back, 4.0, 50.0), newTestOrder(t, Lay, 3.0, 10.0)},
+		"away": {newTestOrder(t, Back, 4.0, 60.0), newTestOrder(t, Lay, 3.0, 10.0)},
+	})
+
+	d, err := m.Depth("home", 3)
+
+	assert.Nil(t, err)
+	assert.Len(t, d.Lay, 2)
+	assert.Equal(t, testPrice(2), d.Lay[0].Price)
+	assert.Equal(t, testAmount(100), d.Lay[0].Volume)
+	assert.Equal(t, 0, d.Lay[0].Orders)
+	assert.Equal(t, testPrice(1.9), d.Lay[1].Price)
+	assert.Len(t, d.Back, 1)
+	assert.Equal(t, testPrice(3), d.Back[0].Price)
+	assert.Equal(t, testAmount(10), d.Back[0].Volume)
+
+	_, err = m.Depth("unknown", 3)
+	assert.Equal(t, ErrSelectionNotFound, err)
+}
+
+func TestCrossMatchBack(t *testing.T) {
+	draw := newTestOrder(t, Back, 4.0, 50.0)
+	away := newTestOrder(t, Back, 4.0, 50.0)
+	m := newTestMarket(t, map[string][]*Order{
+		"draw": {draw},
+		"away": {away},
+	})
+	o := newTestOrder(t, Back, 2.0, 60.0)
+
+	trades, p, err := m.AddOrder("home", o)
+
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+	assertTrades(t, []Trade{
+		{Seq: 1, Selection: "home", TakerId: o.Id, Side: Back, Price: testPrice(2), Stake: testAmount(60)},
+		{Seq: 2, Selection: "draw", MakerId: draw.Id, TakerId: o.Id, Side: Lay, Price: draw.Price, Stake: testAmount(30)},
+		{Seq: 3, Selection: "away", MakerId: away.Id, TakerId: o.Id, Side: Lay, Price: away.Price, Stake: testAmount(30)},
+	}, trades)
+	assert.Equal(t, testAmount(20), draw.Stake)
+	assert.Equal(t, testAmount(20), away.Stake)
+}
+
+func TestCrossMatchPrefersBetterPrice(t *testing.T) {
+	lay := newTestOrder(t, Lay, 2.2, 10.0)
+	m := newTestMarket(t, map[string][]*Order{
+		"home": {lay},
+		"draw": {newTestOrder(t, Back, 4.0, 50.0)},
+		"away": {newTestOrder(t, Back, 4.0, 50.0)},
+	})
+	o := newTestOrder(t, Back, 2.0, 30.0)
+
+	trades, p, err := m.AddOrder("home", o)
+
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+	assert.Len(t, trades, 4)
+	assert.Equal(t, lay.Id, trades[0].MakerId)
+	assert.Equal(t, testAmount(10), trades[0].Stake)
+	assert.Empty(t, trades[1].MakerId)
+	assert.Equal(t, testAmount(20), trades[1].Stake)
+}
+
+func TestCrossMatchRoundsToLadder(t *testing.T) {
+	m := newTestMarket(t, map[string][]*Order{
+		"draw": {newTestOrder(t, Back, 3.0, 100.0), newTestOrder(t, Lay, 2.5, 100.0)},
+		"away": {newTestOrder(t, Back, 5.0, 100.0), newTestOrder(t, Lay, 4.0, 100.0)},
+	})
+	home, _ := m.Book("home")
+
+	price, volume, ok := home.cross.best(Back)
+	assert.True(t, ok)
+	assert.Equal(t, testPrice(2.14), price, price.String())
+	assert.Equal(t, testAmount(140.18), volume, volume.String())
+
+	price, _, ok = home.cross.best(Lay)
+	assert.True(t, ok)
+	assert.Equal(t, testPrice(2.86), price, price.String())
+}
+
+func TestCrossMatchLay(t *testing.T) {
+	draw := newTestOrder(t, Lay, 4.0, 50.0)
+	away := newTestOrder(t, Lay, 4.0, 50.0)
+	m := newTestMarket(t, map[string][]*Order{
+		"draw": {draw},
+		"away": {away},
+	})
+	o := newTestOrder(t, Lay, 2.0, 150.0)
+
+	trades, p, err := m.AddOrder("home", o)
+
+	assert.Nil(t, err)
+	assert.Equal(t, o, p)
+	assert.Equal(t, testAmount(50), o.Stake)
+	assert.Len(t, trades, 3)
+	assert.Equal(t, testAmount(100), trades[0].Stake)
+	assert.Equal(t, Back, trades[1].Side)
+	assert.Zero(t, m.books["draw"].layLevels.Len())
+	assert.Zero(t, m.books["away"].layLevels.Len())
+	assert.Equal(t, o.Price, m.books["home"].BestLay())
+}
+
+func TestCrossMatchNoOverround(t *testing.T) {
+	m := newTestMarket(t, map[string][]*Order{
+		"draw": {newTestOrder(t, Back, 2.0, 50.0)},
+		"away": {newTestOrder(t, Back, 2.0, 50.0)},
+	})
+	home, _ := m.Book("home")
+
+	_, _, ok := home.cross.best(Back)
+	assert.False(t, ok)
+
+	trades, _, err := m.AddOrder("home", newTestOrder(t, Back, 1.01, 10.0))
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+}
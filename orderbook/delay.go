@@ -0,0 +1,75 @@
+package orderbook
+
+import (
+	"time"
+)
+
+// held - in-play order waiting for bet delay to pass
+type held struct {
+	selection   string
+	order       *Order
+	due         time.Time
+	suspensions uint64 // suspensions of the market when order was submitted
+}
+
+// hold - keeps order aside until bet delay of the market passes
+func (m *Market) hold(selection string, o *Order) {
+	o.Status = Pending
+	m.held = append(m.held, &held{
+		selection:   selection,
+		order:       o,
+		due:         m.clock().Add(m.BetDelay),
+		suspensions: m.suspensions,
+	})
+}
+
+// holds - checks if order with given id is held by bet delay
+func (m *Market) holds(id string) bool {
+	for _, h := range m.held {
+		if h.order.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// nextRelease - time when the first held order is due
+func (m *Market) nextRelease() (due time.Time, ok bool) {
+	for _, h := range m.held {
+		if !ok || h.due.Before(due) {
+			due, ok = h.due, true
+		}
+	}
+	return
+}
+
+// Release - admits held orders whose bet delay passed by now in the order
+// they were submitted. Order is rejected with ErrMarketSuspended and
+// Cancelled when the market was suspended while it was held, otherwise it is
+// matched as any other order. Funds were checked on submission, as held
+// orders count in owner's exposure. Results carry copies of the orders, as
+// they are consumed after the market may have changed resting ones again
+func (m *Market) Release(now time.Time) (res []Result) {
+	var waiting []*held
+	for _, h := range m.held {
+		if h.due.After(now) {
+			waiting = append(waiting, h)
+			continue
+		}
+
+		r := Result{
+			Command:  Command{Type: PlaceCommand, Selection: h.selection, Order: h.order.detach()},
+			Released: true,
+		}
+		if h.suspensions != m.suspensions {
+			h.order.Status = Cancelled
+			r.Err = ErrMarketSuspended
+		} else {
+			r.Trades, _, r.Err = m.place(h.selection, h.order)
+		}
+		r.Order = h.order.detach()
+		res = append(res, r)
+	}
+	m.held = waiting
+	return
+}
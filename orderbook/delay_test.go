@@ -0,0 +1,175 @@
+package orderbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDelayedMarket - helper to create in-play market with bet delay and
+// clock stopped at now
+func newDelayedMarket(t *testing.T, now time.Time) *Market {
+	t.Helper()
+
+	m, _ := NewMarket("1", "home", "away")
+	m.BetDelay = 5 * time.Second
+	m.clock = func() time.Time { return now }
+	if _, _, err := m.TurnInPlay(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMarketBetDelay(t *testing.T) {
+	now := time.Now()
+	m := newDelayedMarket(t, now)
+	lay := newTestOrder(t, Lay, 2.0, 10.0)
+	back := newTestOrder(t, Back, 2.0, 10.0)
+
+	for _, o := range []*Order{lay, back} {
+		trades, partial, err := m.AddOrder("home", o)
+		assert.Nil(t, err)
+		assert.Empty(t, trades)
+		assert.Nil(t, partial)
+		assert.Equal(t, Pending, o.Status)
+	}
+	home, _ := m.Book("home")
+	assert.Empty(t, home.orders)
+
+	_, _, err := m.AddOrder("home", back)
+	assert.Equal(t, ErrOrderExists, err)
+
+	due, ok := m.nextRelease()
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(m.BetDelay), due)
+	assert.Empty(t, m.Release(now.Add(4*time.Second)))
+
+	res := m.Release(due)
+
+	assert.Len(t, res, 2)
+	assert.Nil(t, res[0].Err)
+	assert.Equal(t, lay.Id, res[0].Order.Id)
+	assert.Equal(t, Resting, res[0].Order.Status)
+	assert.True(t, res[0].Released)
+	assert.Len(t, res[1].Trades, 1)
+	assert.Equal(t, Filled, back.Status)
+	assert.Equal(t, Filled, lay.Status)
+	_, ok = m.nextRelease()
+	assert.False(t, ok)
+}
+
+func TestMarketBetDelaySuspended(t *testing.T) {
+	now := time.Now()
+	m := newDelayedMarket(t, now)
+	o := newOwnedOrder(t, "alice", Back, 3.0, 10.0)
+	_, _, err := m.AddOrder("home", o)
+	assert.Nil(t, err)
+
+	// goal is scored while the order is held
+	assert.Nil(t, m.Suspend())
+	assert.Nil(t, m.Reopen())
+
+	res := m.Release(now.Add(m.BetDelay))
+
+	assert.Len(t, res, 1)
+	assert.Equal(t, ErrMarketSuspended, res[0].Err)
+	assert.Equal(t, Cancelled, o.Status)
+	assert.True(t, m.Exposure("alice").IsZero())
+
+	// orders submitted after reopening are admitted
+	o = newTestOrder(t, Back, 3.0, 10.0)
+	_, _, err = m.AddOrder("home", o)
+	assert.Nil(t, err)
+	res = m.Release(now.Add(m.BetDelay))
+	assert.Nil(t, res[0].Err)
+	assert.Equal(t, Resting, o.Status)
+}
+
+func TestMarketBetDelayFundsAndClose(t *testing.T) {
+	now := time.Now()
+	m := newDelayedMarket(t, now)
+	m.Funds = testFunds{"alice": decimal.NewFromInt(15)}
+
+	first := newOwnedOrder(t, "alice", Back, 3.0, 10.0)
+	_, _, err := m.AddOrder("home", first)
+	assert.Nil(t, err)
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+
+	// held orders count in exposure
+	_, _, err = m.AddOrder("home", newOwnedOrder(t, "alice", Back, 3.0, 10.0))
+	assert.Equal(t, ErrInsufficientFunds, err)
+
+	res := m.Release(now.Add(m.BetDelay))
+	assert.Len(t, res, 1)
+	assert.Nil(t, res[0].Err)
+
+	held := newOwnedOrder(t, "alice", Lay, 3.0, 1.0)
+	_, _, err = m.AddOrder("home", held)
+	assert.Nil(t, err)
+
+	cancelled := m.Close()
+	assert.ElementsMatch(t, []*Order{first, held}, cancelled)
+	assert.Equal(t, Cancelled, held.Status)
+	assert.Empty(t, m.Release(now.Add(time.Hour)))
+}
+
+func TestMarketBetDelayAmend(t *testing.T) {
+	now := time.Now()
+	m := newDelayedMarket(t, now)
+	lay := newTestOrder(t, Lay, 2.0, 10.0)
+	back := newOwnedOrder(t, "alice", Back, 3.0, 10.0)
+	for _, o := range []*Order{lay, back} {
+		_, _, err := m.AddOrder("home", o)
+		assert.Nil(t, err)
+	}
+	m.Release(now.Add(m.BetDelay))
+	assert.Equal(t, Resting, back.Status)
+
+	// reducing stake keeps the price and applies at once
+	trades, o, err := m.AmendOrder(back.Id, decimal.NewFromInt(3), decimal.NewFromInt(8))
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Equal(t, Resting, o.Status)
+
+	// new price crossing the lay is held
+	trades, o, err = m.AmendOrder(back.Id, decimal.NewFromInt(2), decimal.NewFromInt(8))
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Equal(t, Pending, o.Status)
+	home, _ := m.Book("home")
+	assert.Empty(t, home.Orders(Back))
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(8)))
+
+	res := m.Release(now.Add(m.BetDelay))
+	assert.Len(t, res, 1)
+	assert.Len(t, res[0].Trades, 1)
+	assert.Equal(t, Filled, back.Status)
+	assert.Equal(t, testAmount(2), lay.Stake)
+}
+
+func TestMarketBetDelayAmendSuspended(t *testing.T) {
+	now := time.Now()
+	m := newDelayedMarket(t, now)
+	lay := newTestOrder(t, Lay, 2.0, 10.0)
+	back := newOwnedOrder(t, "alice", Back, 3.0, 10.0)
+	for _, o := range []*Order{lay, back} {
+		_, _, err := m.AddOrder("home", o)
+		assert.Nil(t, err)
+	}
+	m.Release(now.Add(m.BetDelay))
+
+	_, _, err := m.AmendOrder(back.Id, decimal.NewFromInt(2), decimal.NewFromInt(10))
+	assert.Nil(t, err)
+	assert.Nil(t, m.Suspend())
+	assert.Nil(t, m.Reopen())
+
+	res := m.Release(now.Add(m.BetDelay))
+	assert.Len(t, res, 1)
+	assert.Equal(t, ErrMarketSuspended, res[0].Err)
+	assert.Empty(t, res[0].Trades)
+	assert.Equal(t, Cancelled, back.Status)
+	assert.Equal(t, testAmount(10), lay.Stake)
+	assert.True(t, m.Exposure("alice").IsZero())
+}
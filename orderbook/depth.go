@@ -0,0 +1,63 @@
+package orderbook
+
+import (
+	"sort"
+)
+
+// Level - aggregated price level of DOM
+type Level struct {
+	Price  Price  `json:"price"`
+	Volume Amount `json:"volume"`
+	Orders int    `json:"orders"`
+}
+
+// Depth - resting backs and lays of the book, each side sorted from the best price
+type Depth struct {
+	Back []Level `json:"back"`
+	Lay  []Level `json:"lay"`
+}
+
+// sorted - returns price levels of the side from the best price
+func (lv *levels) sorted() []*Limit {
+	limits := make([]*Limit, len(lv.heap))
+	copy(limits, lv.heap)
+	sort.Slice(limits, func(i, j int) bool {
+		if lv.side == Back {
+			return limits[i].Price < limits[j].Price
+		}
+		return limits[i].Price > limits[j].Price
+	})
+	return limits
+}
+
+// top - returns n best levels of the side, all levels when n <= 0
+func (lv *levels) top(n int) []Level {
+	limits := lv.sorted()
+
+	if n > 0 && n < len(limits) {
+		limits = limits[:n]
+	}
+
+	res := make([]Level, 0, len(limits))
+	for _, l := range limits {
+		res = append(res, Level{
+			Price:  l.Price,
+			Volume: l.TotalVolume,
+			Orders: l.Orders.Len(),
+		})
+	}
+	return res
+}
+
+// Depth - returns n best price levels of both sides
+func (ob *Orderbook) Depth(n int) Depth {
+	return Depth{
+		Back: ob.backLevels.top(n),
+		Lay:  ob.layLevels.top(n),
+	}
+}
+
+// FullDepth - returns every price level of both sides
+func (ob *Orderbook) FullDepth() Depth {
+	return ob.Depth(0)
+}
@@ -0,0 +1,48 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderbookDepth(t *testing.T) {
+	ob := NewOrderbook()
+	orders := []*Order{
+		newTestOrder(t, Back, 2.2, 10.0),
+		newTestOrder(t, Back, 2.1, 5.0),
+		newTestOrder(t, Back, 2.1, 15.0),
+		newTestOrder(t, Back, 2.5, 7.0),
+		newTestOrder(t, Lay, 1.9, 3.0),
+		newTestOrder(t, Lay, 2.0, 4.0),
+	}
+	for _, o := range orders {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	d := ob.Depth(2)
+
+	assert.Len(t, d.Back, 2)
+	assert.Equal(t, testPrice(2.1), d.Back[0].Price)
+	assert.Equal(t, testAmount(20.0), d.Back[0].Volume)
+	assert.Equal(t, 2, d.Back[0].Orders)
+	assert.Equal(t, testPrice(2.2), d.Back[1].Price)
+	assert.Len(t, d.Lay, 2)
+	assert.Equal(t, testPrice(2.0), d.Lay[0].Price)
+	assert.Equal(t, testPrice(1.9), d.Lay[1].Price)
+	assert.Equal(t, 1, d.Lay[1].Orders)
+
+	full := ob.FullDepth()
+
+	assert.Len(t, full.Back, 3)
+	assert.Equal(t, testPrice(2.5), full.Back[2].Price)
+	assert.Len(t, full.Lay, 2)
+}
+
+func TestOrderbookDepthEmpty(t *testing.T) {
+	d := NewOrderbook().Depth(5)
+
+	assert.Empty(t, d.Back)
+	assert.Empty(t, d.Lay)
+}
@@ -0,0 +1,188 @@
+package orderbook
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Liability - amount the order risks if its unmatched stake gets matched:
+// stake for backs and stake * (price - 1) for lays
+func (o *Order) Liability() decimal.Decimal {
+	return liability(o.Side, o.Price, o.Stake)
+}
+
+// Profit - amount the order wins if its unmatched stake gets matched:
+// stake * (price - 1) for backs and stake for lays
+func (o *Order) Profit() decimal.Decimal {
+	return liability(o.Side.Opposite(), o.Price, o.Stake)
+}
+
+func liability(side Side, price Price, stake Amount) decimal.Decimal {
+	if side == Back {
+		return stake.Decimal()
+	}
+	return decimal.New(int64(stake)*int64(price-priceOne), amountExp+priceExp)
+}
+
+// FundsChecker - supplies funds owner can risk in the market
+type FundsChecker interface {
+	Available(owner string) (decimal.Decimal, error)
+}
+
+// account - positions of an owner in the market
+type account struct {
+	pnl    map[string]decimal.Decimal   // matched profit or loss if selection wins
+	orders map[string]map[string]*Order // unmatched orders by selection and id
+}
+
+func newAccount() *account {
+	return &account{
+		pnl:    make(map[string]decimal.Decimal),
+		orders: make(map[string]map[string]*Order),
+	}
+}
+
+// rest - records unmatched order of the selection
+func (a *account) rest(selection string, o *Order) {
+	orders, ok := a.orders[selection]
+	if !ok {
+		orders = make(map[string]*Order)
+		a.orders[selection] = orders
+	}
+	orders[o.Id] = o
+}
+
+// remove - forgets order which is no longer unmatched
+func (a *account) remove(selection string, o *Order) {
+	delete(a.orders[selection], o.Id)
+}
+
+// account - returns positions of the owner, creating them on first use
+func (m *Market) account(owner string) *account {
+	a, ok := m.accounts[owner]
+	if !ok {
+		a = newAccount()
+		m.accounts[owner] = a
+	}
+	return a
+}
+
+// fill - records matched part of the order placed on the selection
+func (m *Market) fill(selection string) func(o *Order, price Price, stake Amount) {
+	return func(o *Order, price Price, stake Amount) {
+		m.record(selection, o.Owner, o.Side, price, stake)
+
+		if o.Stake == 0 {
+			m.account(o.Owner).remove(selection, o)
+		}
+	}
+}
+
+// cancel - forgets resting order of the selection cancelled by the book
+func (m *Market) cancel(selection string) func(o *Order) {
+	return func(o *Order) {
+		m.account(o.Owner).remove(selection, o)
+	}
+}
+
+// record - adds matched bet of the owner to profit or loss of every outcome
+func (m *Market) record(selection, owner string, side Side, price Price, stake Amount) {
+	a := m.account(owner)
+	profit := liability(side.Opposite(), price, stake)
+	loss := liability(side, price, stake)
+
+	for _, s := range m.selections {
+		won := (s == selection) == (side == Back)
+		if won {
+			a.pnl[s] = a.pnl[s].Add(profit)
+		} else {
+			a.pnl[s] = a.pnl[s].Sub(loss)
+		}
+	}
+}
+
+// risk - unmatched stake counted in exposure only with its loss: liability
+// of orders, stake of SP backs and liability of SP lays
+type risk struct {
+	selection string
+	side      Side
+	loss      decimal.Decimal
+}
+
+func orderRisk(selection string, o *Order) *risk {
+	return &risk{selection: selection, side: o.Side, loss: o.Liability()}
+}
+
+// outcomes - worst-case profit or loss of the owner for every winning
+// selection. Unmatched and held orders and SP bets, including optional extra
+// risk about to be taken, are counted only with their losses
+func (m *Market) outcomes(owner string, extra *risk) map[string]decimal.Decimal {
+	a := m.account(owner)
+	res := make(map[string]decimal.Decimal, len(m.selections))
+	for _, s := range m.selections {
+		res[s] = a.pnl[s]
+	}
+
+	add := func(r *risk) {
+		for _, s := range m.selections {
+			if (s == r.selection) != (r.side == Back) {
+				res[s] = res[s].Sub(r.loss)
+			}
+		}
+	}
+	for s, orders := range a.orders {
+		for _, o := range orders {
+			add(orderRisk(s, o))
+		}
+	}
+	for _, h := range m.held {
+		if h.order.Owner == owner {
+			add(orderRisk(h.selection, h.order))
+		}
+	}
+	for s, bets := range m.spBets {
+		for _, b := range bets {
+			if b.Owner == owner {
+				add(&risk{selection: s, side: b.Side, loss: b.Amount})
+			}
+		}
+	}
+	if extra != nil {
+		add(extra)
+	}
+	return res
+}
+
+// exposure - worst-case loss of the owner over all outcomes of the market
+func (m *Market) exposure(owner string, extra *risk) decimal.Decimal {
+	worst := decimal.Zero
+	for _, pnl := range m.outcomes(owner, extra) {
+		if pnl.LessThan(worst) {
+			worst = pnl
+		}
+	}
+	return worst.Neg()
+}
+
+// Exposure - worst-case loss of the owner in the market across all
+// selections, including unmatched orders and SP bets as if they were matched
+func (m *Market) Exposure(owner string) decimal.Decimal {
+	return m.exposure(owner, nil)
+}
+
+// checkFunds - rejects order or SP bet when owner's exposure with its risk
+// would exceed funds available to the owner
+func (m *Market) checkFunds(owner string, extra *risk) error {
+	if m.Funds == nil {
+		return nil
+	}
+
+	available, err := m.Funds.Available(owner)
+	if err != nil {
+		return err
+	}
+
+	if m.exposure(owner, extra).GreaterThan(available) {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package orderbook
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+type testFunds map[string]decimal.Decimal
+
+func (f testFunds) Available(owner string) (decimal.Decimal, error) {
+	available, ok := f[owner]
+	if !ok {
+		return decimal.Zero, errors.New("unknown account")
+	}
+	return available, nil
+}
+
+// newOwnedOrder - helper to create order of the owner
+func newOwnedOrder(t *testing.T, owner string, side Side, price, stake float64) *Order {
+	t.Helper()
+
+	o := newTestOrder(t, side, price, stake)
+	o.Owner = owner
+	return o
+}
+
+func TestOrderLiabilityAndProfit(t *testing.T) {
+	back := newTestOrder(t, Back, 3.0, 10.0)
+	lay := newTestOrder(t, Lay, 3.0, 10.0)
+
+	assert.True(t, back.Liability().Equal(decimal.NewFromInt(10)))
+	assert.True(t, back.Profit().Equal(decimal.NewFromInt(20)))
+	assert.True(t, lay.Liability().Equal(decimal.NewFromInt(20)))
+	assert.True(t, lay.Profit().Equal(decimal.NewFromInt(10)))
+}
+
+func TestMarketExposureUnmatched(t *testing.T) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	assert.True(t, m.Exposure("alice").IsZero())
+
+	_, _, err := m.AddOrder("home", newOwnedOrder(t, "alice", Back, 3.0, 10.0))
+	assert.Nil(t, err)
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+
+	lay := newOwnedOrder(t, "alice", Lay, 4.0, 10.0)
+	_, _, err = m.AddOrder("away", lay)
+	assert.Nil(t, err)
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(40)))
+
+	_, err = m.CancelOrder(lay.Id)
+	assert.Nil(t, err)
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+}
+
+func TestMarketExposureMatched(t *testing.T) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	_, _, err := m.AddOrder("home", newOwnedOrder(t, "bob", Lay, 2.0, 10.0))
+	assert.Nil(t, err)
+	_, _, err = m.AddOrder("home", newOwnedOrder(t, "alice", Back, 2.0, 10.0))
+	assert.Nil(t, err)
+
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+	assert.True(t, m.Exposure("bob").Equal(decimal.NewFromInt(10)))
+	assert.Empty(t, m.account("bob").orders["home"])
+
+	_, _, err = m.AddOrder("home", newOwnedOrder(t, "alice", Lay, 2.0, 10.0))
+	assert.Nil(t, err)
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+
+	_, _, err = m.AddOrder("home", newOwnedOrder(t, "carol", Back, 2.0, 10.0))
+	assert.Nil(t, err)
+	assert.True(t, m.Exposure("alice").IsZero())
+}
+
+func TestMarketFundsCheck(t *testing.T) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	m.Funds = testFunds{"alice": decimal.NewFromInt(15)}
+
+	_, _, err := m.AddOrder("home", newOwnedOrder(t, "alice", Back, 3.0, 10.0))
+	assert.Nil(t, err)
+
+	_, _, err = m.AddOrder("draw", newOwnedOrder(t, "alice", Back, 3.0, 10.0))
+	assert.Equal(t, ErrInsufficientFunds, err)
+	draw, _ := m.Book("draw")
+	assert.Empty(t, draw.orders)
+
+	_, _, err = m.AddOrder("home", newOwnedOrder(t, "alice", Lay, 1.5, 10.0))
+	assert.Nil(t, err)
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+
+	_, _, err = m.AddOrder("home", newOwnedOrder(t, "bob", Back, 3.0, 10.0))
+	assert.EqualError(t, err, "unknown account")
+}
+
+func TestMarketSPBetFundsCheck(t *testing.T) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	m.Funds = testFunds{"alice": decimal.NewFromInt(15)}
+
+	assert.Nil(t, m.PlaceSPBet("home", newTestSPBet(t, "alice", Back, 0, 10)))
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+
+	err := m.PlaceSPBet("draw", newTestSPBet(t, "alice", Lay, 0, 10))
+	assert.Equal(t, ErrInsufficientFunds, err)
+	assert.Empty(t, m.spBets["draw"])
+
+	assert.Nil(t, m.PlaceSPBet("home", newTestSPBet(t, "alice", Lay, 0, 10)))
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+
+	_, _, err = m.AddOrder("away", newOwnedOrder(t, "alice", Back, 3.0, 10.0))
+	assert.Equal(t, ErrInsufficientFunds, err)
+}
@@ -0,0 +1,49 @@
+package orderbook
+
+import (
+	"container/list"
+
+	"github.com/shopspring/decimal"
+)
+
+// NewIcebergOrder - creates order showing only peak of its stake in DOM. The
+// rest is kept in hidden reserve and displayed peak by peak as it is matched
+func NewIcebergOrder(side Side, price, stake, peak decimal.Decimal) (*Order, error) {
+	p, err := NewAmount(peak)
+	if err != nil || p <= 0 || peak.GreaterThanOrEqual(stake) {
+		return nil, ErrInvalidPeak
+	}
+
+	o, err := NewOrder(side, price, stake)
+	if err != nil {
+		return nil, err
+	}
+	o.Peak = p
+	return o, nil
+}
+
+func (o *Order) iceberg() bool {
+	return o.Peak > 0
+}
+
+// visible - part of unmatched stake displayed in DOM
+func (o *Order) visible() Amount {
+	if o.iceberg() && o.limit != nil {
+		return o.shown
+	}
+	return o.Stake
+}
+
+// Hidden - part of unmatched stake of resting order not displayed in DOM
+func (o *Order) Hidden() Amount {
+	return o.Stake - o.visible()
+}
+
+// replenish - displays next peak of iceberg order from its hidden reserve.
+// The new peak loses time priority and joins the back of the level
+func (l *Limit) replenish(e *list.Element) {
+	o := e.Value.(*Order)
+	o.shown = minAmount(o.Peak, o.Stake)
+	l.TotalVolume += o.shown
+	l.Orders.MoveToBack(e)
+}
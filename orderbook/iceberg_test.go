@@ -0,0 +1,149 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestIceberg - helper to create iceberg order
+func newTestIceberg(t *testing.T, side Side, price, stake, peak float64) *Order {
+	t.Helper()
+
+	o, err := NewIcebergOrder(side, decimal.NewFromFloat(price), decimal.NewFromFloat(stake), decimal.NewFromFloat(peak))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestNewIcebergOrder(t *testing.T) {
+	price, stake := decimal.NewFromFloat(2.0), decimal.NewFromInt(100)
+
+	_, err := NewIcebergOrder(Lay, price, stake, decimal.Zero)
+	assert.Equal(t, ErrInvalidPeak, err)
+	_, err = NewIcebergOrder(Lay, price, stake, stake)
+	assert.Equal(t, ErrInvalidPeak, err)
+	_, err = NewIcebergOrder(Lay, decimal.NewFromFloat(2.01), stake, decimal.NewFromInt(20))
+	assert.Equal(t, ErrPriceNotOnLadder, err)
+
+	o, err := NewIcebergOrder(Lay, price, stake, decimal.NewFromInt(20))
+	assert.Nil(t, err)
+	assert.Equal(t, testAmount(20), o.Peak)
+
+	o = newTestOrder(t, Lay, 2.0, 10.0)
+	o.Peak = -1
+	_, _, err = NewOrderbook().AddOrder(o)
+	assert.Equal(t, ErrInvalidPeak, err)
+}
+
+func TestIcebergDisplaysPeak(t *testing.T) {
+	ob := NewOrderbook()
+	o := newTestIceberg(t, Lay, 2.0, 100.0, 20.0)
+	_, _, err := ob.AddOrder(o)
+	assert.Nil(t, err)
+
+	assert.Equal(t, testAmount(20), ob.layLevels.get(o.Price).TotalVolume)
+	assert.Equal(t, testAmount(80), o.Hidden())
+	assert.Equal(t, testAmount(20), ob.Depth(1).Lay[0].Volume)
+}
+
+func TestIcebergReplenishLosesPriority(t *testing.T) {
+	ob := NewOrderbook()
+	iceberg := newTestIceberg(t, Lay, 2.0, 100.0, 20.0)
+	plain := newTestOrder(t, Lay, 2.0, 10.0)
+	for _, o := range []*Order{iceberg, plain} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+	back := newTestOrder(t, Back, 2.0, 25.0)
+
+	trades, _, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	price := testPrice(2.0)
+	assertTrades(t, []Trade{
+		{Seq: 1, MakerId: iceberg.Id, TakerId: back.Id, Side: Back, Price: price, Stake: testAmount(20)},
+		{Seq: 2, MakerId: plain.Id, TakerId: back.Id, Side: Back, Price: price, Stake: testAmount(5)},
+	}, trades)
+	assert.Equal(t, []*Order{plain, iceberg}, ob.Orders(Lay))
+	assert.Equal(t, testAmount(80), iceberg.Stake)
+	assert.Equal(t, testAmount(60), iceberg.Hidden())
+	assert.Equal(t, testAmount(25), ob.layLevels.get(price).TotalVolume)
+}
+
+func TestIcebergMatchesHiddenReserve(t *testing.T) {
+	ob := NewOrderbook()
+	iceberg := newTestIceberg(t, Lay, 2.0, 50.0, 20.0)
+	_, _, err := ob.AddOrder(iceberg)
+	assert.Nil(t, err)
+
+	trades, partial, err := ob.AddOrder(newTestOrder(t, Back, 2.0, 45.0))
+
+	assert.Nil(t, err)
+	assert.Nil(t, partial)
+	assert.Len(t, trades, 3)
+	assert.Equal(t, testAmount(5), trades[2].Stake)
+	assert.Equal(t, testAmount(5), iceberg.Stake)
+	assert.Zero(t, iceberg.Hidden())
+	assert.Equal(t, testAmount(5), ob.layLevels.get(iceberg.Price).TotalVolume)
+
+	// fill or kill sees hidden reserve
+	iceberg = newTestIceberg(t, Lay, 2.0, 100.0, 20.0)
+	_, _, err = ob.AddOrder(iceberg)
+	assert.Nil(t, err)
+	fok := newTestOrder(t, Back, 2.0, 60.0)
+	fok.TimeInForce = FillOrKill
+	_, _, err = ob.AddOrder(fok)
+	assert.Nil(t, err)
+	assert.Equal(t, Filled, fok.Status)
+}
+
+func TestIcebergReduceUsesHiddenFirst(t *testing.T) {
+	ob := NewOrderbook()
+	o := newTestIceberg(t, Back, 3.0, 100.0, 20.0)
+	_, _, err := ob.AddOrder(o)
+	assert.Nil(t, err)
+	limit := ob.backLevels.get(o.Price)
+
+	_, err = ob.ReduceOrder(o.Id, testAmount(70))
+	assert.Nil(t, err)
+	assert.Equal(t, testAmount(30), o.Stake)
+	assert.Equal(t, testAmount(20), limit.TotalVolume)
+
+	_, err = ob.ReduceOrder(o.Id, testAmount(15))
+	assert.Nil(t, err)
+	assert.Zero(t, o.Hidden())
+	assert.Equal(t, testAmount(15), limit.TotalVolume)
+}
+
+func TestIcebergFillOrKillBehindOwnOrder(t *testing.T) {
+	ob := NewOrderbook()
+	iceberg := newTestIceberg(t, Lay, 2.0, 20.0, 5.0)
+	iceberg.Owner = "bob"
+	own := newOwnedOrder(t, "alice", Lay, 2.0, 5.0)
+	for _, o := range []*Order{iceberg, own} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	// replenished peak joins the level behind own order, so only the first
+	// peak is available
+	back := newOwnedOrder(t, "alice", Back, 2.0, 15.0)
+	back.TimeInForce = FillOrKill
+	trades, _, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Equal(t, Killed, back.Status)
+	assert.Equal(t, testAmount(20), iceberg.Stake)
+
+	back = newOwnedOrder(t, "alice", Back, 2.0, 5.0)
+	back.TimeInForce = FillOrKill
+	trades, _, err = ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, Filled, back.Status)
+}
@@ -0,0 +1,324 @@
+package orderbook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Entry - command accepted by the market as it was submitted, Seq numbers
+// entries of the market journal from 1 without gaps
+type Entry struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Command Command   `json:"command"`
+}
+
+// Journal - durable append-only log of accepted commands together with the
+// latest snapshot of the market
+type Journal interface {
+	Append(e Entry) error
+	Snapshot(s *Snapshot) error
+}
+
+// Snapshot - complete state of the market after journal entry Seq. Funds
+// checker is not a part of the state and has to be set on restored market
+type Snapshot struct {
+	Seq         uint64                                `json:"seq"`
+	Id          string                                `json:"id"`
+	BetDelay    time.Duration                         `json:"bet_delay"`
+	Status      MarketStatus                          `json:"status"`
+	Resume      MarketStatus                          `json:"resume"`
+	Suspensions uint64                                `json:"suspensions"`
+	TradeSeq    uint64                                `json:"trade_seq"`
+	Selections  []string                              `json:"selections"`
+	Orders      map[string][]snapshotOrder            `json:"orders"` // resting orders by selection, backs first in priority
+	Held        []snapshotHeld                        `json:"held"`
+	SPBets      map[string][]SPBet                    `json:"sp_bets"`
+	PnL         map[string]map[string]decimal.Decimal `json:"pnl"` // matched profit or loss by owner and selection
+}
+
+type snapshotOrder struct {
+	Order
+	Shown Amount `json:"shown"`
+}
+
+type snapshotHeld struct {
+	Selection   string    `json:"selection"`
+	Order       Order     `json:"order"`
+	Due         time.Time `json:"due"`
+	Suspensions uint64    `json:"suspensions"`
+}
+
+// at - runs fn with market clock stopped at now
+func (m *Market) at(now time.Time, fn func()) {
+	clock := m.clock
+	m.clock = func() time.Time { return now }
+	defer func() { m.clock = clock }()
+	fn()
+}
+
+// Snapshot - copies current state of the market
+func (m *Market) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Seq:         m.entry,
+		Id:          m.Id,
+		BetDelay:    m.BetDelay,
+		Status:      m.status,
+		Resume:      m.resume,
+		Suspensions: m.suspensions,
+		TradeSeq:    m.seq,
+		Selections:  m.Selections(),
+		Orders:      make(map[string][]snapshotOrder),
+		SPBets:      make(map[string][]SPBet),
+		PnL:         make(map[string]map[string]decimal.Decimal),
+	}
+
+	for _, sel := range m.selections {
+		ob := m.books[sel]
+		for _, side := range []Side{Back, Lay} {
+			for _, o := range ob.Orders(side) {
+				so := snapshotOrder{Order: *o, Shown: o.shown}
+				so.limit = nil
+				s.Orders[sel] = append(s.Orders[sel], so)
+			}
+		}
+		for _, b := range m.spBets[sel] {
+			s.SPBets[sel] = append(s.SPBets[sel], *b)
+		}
+	}
+
+	for _, h := range m.held {
+		s.Held = append(s.Held, snapshotHeld{Selection: h.selection, Order: *h.order, Due: h.due, Suspensions: h.suspensions})
+	}
+
+	for owner, a := range m.accounts {
+		if len(a.pnl) == 0 {
+			continue
+		}
+		pnl := make(map[string]decimal.Decimal, len(a.pnl))
+		for sel, v := range a.pnl {
+			pnl[sel] = v
+		}
+		s.PnL[owner] = pnl
+	}
+	return s
+}
+
+// Restore - creates market from the snapshot
+func Restore(s *Snapshot) (*Market, error) {
+	m, err := NewMarket(s.Id, s.Selections...)
+	if err != nil {
+		return nil, err
+	}
+	m.BetDelay = s.BetDelay
+	m.status = s.Status
+	m.resume = s.Resume
+	m.suspensions = s.Suspensions
+	m.seq = s.TradeSeq
+	m.entry = s.Seq
+
+	for sel, orders := range s.Orders {
+		ob, err := m.Book(sel)
+		if err != nil {
+			return nil, err
+		}
+		for _, so := range orders {
+			o := so.Order
+			e, err := ob.PlaceOrder(&o)
+			if err != nil {
+				return nil, err
+			}
+			if o.iceberg() {
+				o.limit.TotalVolume += so.Shown - o.shown
+				o.shown = so.Shown
+			}
+			ob.orders[o.Id] = e
+			m.account(o.Owner).rest(sel, &o)
+		}
+	}
+
+	for _, h := range s.Held {
+		o := h.Order
+		m.held = append(m.held, &held{selection: h.Selection, order: &o, due: h.Due, suspensions: h.Suspensions})
+	}
+
+	for sel, bets := range s.SPBets {
+		for i := range bets {
+			b := bets[i]
+			m.spBets[sel] = append(m.spBets[sel], &b)
+		}
+	}
+
+	for owner, pnl := range s.PnL {
+		a := m.account(owner)
+		for sel, v := range pnl {
+			a.pnl[sel] = v
+		}
+	}
+	return m, nil
+}
+
+// Replay - applies journal entries following the last entry of the market,
+// e.g. restored from snapshot, as they were applied originally. Entries the
+// market already has are skipped. Funds are not checked again, as the entries
+// were accepted
+func Replay(m *Market, entries []Entry) error {
+	funds := m.Funds
+	m.Funds = nil
+	defer func() { m.Funds = funds }()
+
+	for _, e := range entries {
+		if e.Seq <= m.entry {
+			continue
+		}
+		if e.Seq != m.entry+1 {
+			return ErrJournalGap
+		}
+
+		var err error
+		if e.Command.Type == ReleaseCommand {
+			m.Release(e.Time)
+		} else {
+			cmd := e.Command.copy()
+			m.at(e.Time, func() { err = m.execute(cmd).Err })
+		}
+		if err != nil {
+			return fmt.Errorf("orderbook: replay of entry %d: %w", e.Seq, err)
+		}
+		m.entry = e.Seq
+	}
+	return nil
+}
+
+// FileJournal - journal kept in a directory as log of JSON lines next to the
+// latest snapshot. Log is truncated once a snapshot is written
+type FileJournal struct {
+	dir string
+	log *os.File
+}
+
+const (
+	journalLog      = "journal.log"
+	journalSnapshot = "snapshot.json"
+)
+
+// OpenJournal - opens journal in dir, creating it when necessary
+func OpenJournal(dir string) (*FileJournal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, journalLog), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileJournal{dir: dir, log: f}, nil
+}
+
+// Append - writes entry to the log and syncs it to disk
+func (j *FileJournal) Append(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.log.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return j.log.Sync()
+}
+
+// Snapshot - atomically replaces the snapshot and truncates the log
+func (j *FileJournal) Snapshot(s *Snapshot) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(j.dir, journalSnapshot+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(j.dir, journalSnapshot)); err != nil {
+		return err
+	}
+	return j.log.Truncate(0)
+}
+
+// Load - restores market from the snapshot and replays the log after it.
+// Partially written last entry is ignored
+func (j *FileJournal) Load() (*Market, error) {
+	b, err := os.ReadFile(filepath.Join(j.dir, journalSnapshot))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	m, err := Restore(&s)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(j.dir, journalLog))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := readEntries(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := Replay(m, entries); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (j *FileJournal) Close() error {
+	return j.log.Close()
+}
+
+// readEntries - decodes log of JSON lines up to the first incomplete line
+func readEntries(r io.Reader) (entries []Entry, err error) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,208 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+type memJournal struct {
+	entries   []Entry
+	snapshots []*Snapshot
+	fail      error
+}
+
+func (j *memJournal) Append(e Entry) error {
+	if j.fail != nil {
+		return j.fail
+	}
+	j.entries = append(j.entries, e)
+	return nil
+}
+
+func (j *memJournal) Snapshot(s *Snapshot) error {
+	j.snapshots = append(j.snapshots, s)
+	return nil
+}
+
+// runJournaled - helper to run commands of every kind through sequencer of
+// a new market writing to the journal
+func runJournaled(t *testing.T, j Journal, every uint64) *Market {
+	t.Helper()
+
+	m, _ := NewMarket("1", "home", "draw", "away")
+	m.BetDelay = time.Millisecond
+	results := make(chan Result, 256)
+	s := NewSequencer(m, results)
+	s.Journal = j
+	s.SnapshotEvery = every
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	submit := func(cmd Command) Result {
+		t.Helper()
+		res := s.Submit(cmd)
+		assert.Nil(t, res.Err, cmd.Type.String())
+		return res
+	}
+	place := func(selection string, o *Order) *Order {
+		t.Helper()
+		submit(Command{Type: PlaceCommand, Selection: selection, Order: o})
+		return o
+	}
+
+	iceberg := newTestIceberg(t, Lay, 2.0, 50.0, 10.0)
+	iceberg.Owner = "mm"
+	iceberg.Persistence = Persist
+	place("home", iceberg)
+	alice := place("home", newOwnedOrder(t, "alice", Back, 3.0, 10.0))
+	place("home", newOwnedOrder(t, "bob", Back, 2.0, 25.0))
+	carol := newOwnedOrder(t, "carol", Lay, 4.0, 20.0)
+	carol.Persistence = Persist
+	place("away", carol)
+	dave := place("draw", newOwnedOrder(t, "dave", Back, 5.0, 10.0))
+	submit(Command{Type: AmendCommand, OrderId: alice.Id, Price: decimal.NewFromFloat(2.5), Amount: decimal.NewFromInt(8)})
+	submit(Command{Type: ReduceCommand, OrderId: carol.Id, Amount: decimal.NewFromInt(5)})
+	submit(Command{Type: CancelCommand, OrderId: dave.Id})
+	bet, _ := NewSPBet(Back, decimal.Zero, decimal.NewFromInt(10))
+	bet.Owner = "erin"
+	submit(Command{Type: SPBetCommand, Selection: "away", Bet: bet})
+	submit(Command{Type: SuspendCommand})
+	submit(Command{Type: ReopenCommand})
+	submit(Command{Type: TurnInPlayCommand})
+
+	held := place("home", newOwnedOrder(t, "frank", Back, 2.0, 5.0))
+	for released := false; !released; {
+		select {
+		case res := <-results:
+			released = res.Released && res.Order.Id == held.Id && res.Command.Type == PlaceCommand && res.Err == nil && len(res.Trades) > 0
+		case <-time.After(time.Second):
+			t.Fatal("held order was not released")
+		}
+	}
+	place("draw", newOwnedOrder(t, "grace", Lay, 3.0, 10.0))
+
+	s.Stop()
+	return m
+}
+
+// assertSameMarket - compares depth, resting orders and the rest of the
+// state of two markets byte by byte
+func assertSameMarket(t *testing.T, want, got *Market) {
+	t.Helper()
+
+	for _, sel := range want.Selections() {
+		wd, _ := want.Depth(sel, 0)
+		gd, _ := got.Depth(sel, 0)
+		assert.Equal(t, marshal(t, wd), marshal(t, gd), "depth of %s", sel)
+
+		assert.Equal(t, marshal(t, restingOrders(want.books[sel])), marshal(t, restingOrders(got.books[sel])), "orders of %s", sel)
+	}
+	assert.Equal(t, marshal(t, want.Snapshot()), marshal(t, got.Snapshot()))
+}
+
+func restingOrders(ob *Orderbook) map[string]snapshotOrder {
+	res := make(map[string]snapshotOrder, len(ob.orders))
+	for id, e := range ob.orders {
+		o := e.Value.(*Order)
+		res[id] = snapshotOrder{Order: *o, Shown: o.shown}
+	}
+	return res
+}
+
+func marshal(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestJournalReplay(t *testing.T) {
+	j := &memJournal{}
+	m := runJournaled(t, j, 5)
+
+	assert.Len(t, j.entries, 15)
+	assert.Len(t, j.snapshots, 4)
+	assert.Equal(t, ReleaseCommand, j.entries[13].Command.Type)
+	for i, e := range j.entries {
+		assert.Equal(t, uint64(i+1), e.Seq)
+	}
+
+	// from the initial snapshot
+	replayed, err := Restore(j.snapshots[0])
+	assert.Nil(t, err)
+	assert.Nil(t, Replay(replayed, j.entries))
+	assertSameMarket(t, m, replayed)
+
+	// from the latest snapshot
+	restored, err := Restore(j.snapshots[3])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(15), restored.entry)
+	assert.Nil(t, Replay(restored, j.entries))
+	assertSameMarket(t, m, restored)
+
+	restored, _ = Restore(j.snapshots[1])
+	assert.Equal(t, ErrJournalGap, Replay(restored, j.entries[12:]))
+}
+
+func TestJournalReplayJSON(t *testing.T) {
+	j := &memJournal{}
+	m := runJournaled(t, j, 0)
+
+	var s Snapshot
+	assert.Nil(t, json.Unmarshal([]byte(marshal(t, j.snapshots[0])), &s))
+	var entries []Entry
+	assert.Nil(t, json.Unmarshal([]byte(marshal(t, j.entries)), &entries))
+
+	replayed, err := Restore(&s)
+	assert.Nil(t, err)
+	assert.Nil(t, Replay(replayed, entries))
+	assertSameMarket(t, m, replayed)
+}
+
+func TestSequencerJournalFailure(t *testing.T) {
+	j := &memJournal{fail: errors.New("disk full")}
+	m, _ := NewMarket("1", "home", "away")
+	s := NewSequencer(m, nil)
+	s.Journal = j
+	assert.Nil(t, s.Start())
+	defer s.Stop()
+
+	res := s.Submit(Command{Type: PlaceCommand, Selection: "home", Order: newTestOrder(t, Back, 3.0, 10.0)})
+	assert.Equal(t, j.fail, res.Err)
+
+	res = s.Submit(Command{Type: PlaceCommand, Selection: "home", Order: newTestOrder(t, Back, 3.0, 10.0)})
+	assert.Equal(t, ErrSequencerStopped, res.Err)
+}
+
+func TestFileJournal(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "market")
+	j, err := OpenJournal(dir)
+	assert.Nil(t, err)
+	_, err = j.Load()
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	m := runJournaled(t, j, 4)
+
+	// entry torn by a crash is ignored
+	f, err := os.OpenFile(filepath.Join(dir, journalLog), os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"seq":16,"command":{"ty`)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	loaded, err := j.Load()
+	assert.Nil(t, err)
+	assertSameMarket(t, m, loaded)
+	assert.Nil(t, j.Close())
+}
@@ -0,0 +1,175 @@
+package orderbook
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Band - range of prices sharing the same tick size, bounds are inclusive
+type Band struct {
+	From decimal.Decimal
+	To   decimal.Decimal
+	Tick decimal.Decimal
+
+	from, to, tick Price // set by NewLadder
+}
+
+// Ladder - contiguous price bands ordered from the lowest price, created by
+// NewLadder
+type Ladder []Band
+
+// DefaultLadder - Betfair-style odds ladder from 1.01 up to 1000 used to
+// validate order prices. Can be replaced on startup to configure the exchange
+var DefaultLadder = mustLadder(
+	band("1.01", "2", "0.01"),
+	band("2", "3", "0.02"),
+	band("3", "4", "0.05"),
+	band("4", "6", "0.1"),
+	band("6", "10", "0.2"),
+	band("10", "20", "0.5"),
+	band("20", "30", "1"),
+	band("30", "50", "2"),
+	band("50", "100", "5"),
+	band("100", "1000", "10"),
+)
+
+func band(from, to, tick string) Band {
+	return Band{
+		From: decimal.RequireFromString(from),
+		To:   decimal.RequireFromString(to),
+		Tick: decimal.RequireFromString(tick),
+	}
+}
+
+func mustLadder(bands ...Band) Ladder {
+	l, err := NewLadder(bands...)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// NewLadder - creates ladder checking that bands are contiguous, every band
+// is divisible by its tick and no tick is finer than a Price
+func NewLadder(bands ...Band) (Ladder, error) {
+	if len(bands) == 0 || bands[0].From.LessThanOrEqual(minPrice) {
+		return nil, ErrInvalidLadder
+	}
+
+	l := make(Ladder, len(bands))
+	for i, b := range bands {
+		if b.Tick.Sign() <= 0 || b.To.LessThanOrEqual(b.From) {
+			return nil, ErrInvalidLadder
+		}
+		if !b.To.Sub(b.From).Mod(b.Tick).IsZero() {
+			return nil, ErrInvalidLadder
+		}
+		if i > 0 && !bands[i-1].To.Equal(b.From) {
+			return nil, ErrInvalidLadder
+		}
+
+		var err error
+		if b.from, err = NewPrice(b.From); err != nil {
+			return nil, ErrInvalidLadder
+		}
+		if b.to, err = NewPrice(b.To); err != nil {
+			return nil, ErrInvalidLadder
+		}
+		if b.tick, err = NewPrice(b.Tick); err != nil {
+			return nil, ErrInvalidLadder
+		}
+		l[i] = b
+	}
+	return l, nil
+}
+
+// Min - lowest price of the ladder
+func (l Ladder) Min() decimal.Decimal {
+	return l[0].From
+}
+
+// Max - highest price of the ladder
+func (l Ladder) Max() decimal.Decimal {
+	return l[len(l)-1].To
+}
+
+// Valid - checks that price is one of the ladder ticks
+func (l Ladder) Valid(price decimal.Decimal) bool {
+	p, err := NewPrice(price)
+	return err == nil && l.valid(p)
+}
+
+func (l Ladder) valid(price Price) bool {
+	for _, b := range l {
+		if price >= b.from && price <= b.to {
+			return (price-b.from)%b.tick == 0
+		}
+	}
+	return false
+}
+
+// floor - highest ladder price at or below price, the highest one for prices
+// above the ladder
+func (l Ladder) floor(price Price) (Price, bool) {
+	if top := l[len(l)-1].to; price > top {
+		return top, true
+	}
+	for i := len(l) - 1; i >= 0; i-- {
+		if b := l[i]; price >= b.from {
+			return b.from + (price-b.from)/b.tick*b.tick, true
+		}
+	}
+	return 0, false
+}
+
+// ceil - lowest ladder price at or above price, the lowest one for prices
+// below the ladder
+func (l Ladder) ceil(price Price) (Price, bool) {
+	if price < l[0].from {
+		return l[0].from, true
+	}
+	for _, b := range l {
+		if price <= b.to {
+			return b.from + (price-b.from+b.tick-1)/b.tick*b.tick, true
+		}
+	}
+	return 0, false
+}
+
+// Validate - returns ErrPriceNotOnLadder when price is not one of the ticks
+func (l Ladder) Validate(price decimal.Decimal) error {
+	if !l.Valid(price) {
+		return ErrPriceNotOnLadder
+	}
+	return nil
+}
+
+// NextTickUp - returns the lowest ladder price above given price
+func (l Ladder) NextTickUp(price decimal.Decimal) (decimal.Decimal, error) {
+	if price.LessThan(l.Min()) {
+		return l.Min(), nil
+	}
+
+	for _, b := range l {
+		if price.GreaterThanOrEqual(b.From) && price.LessThan(b.To) {
+			steps := price.Sub(b.From).Div(b.Tick).Floor().Add(decimal.NewFromInt(1))
+			return b.From.Add(steps.Mul(b.Tick)), nil
+		}
+	}
+	return decimal.Zero, ErrPriceNotOnLadder
+}
+
+// NextTickDown - returns the highest ladder price below given price
+func (l Ladder) NextTickDown(price decimal.Decimal) (decimal.Decimal, error) {
+	if price.GreaterThan(l.Max()) {
+		return l.Max(), nil
+	}
+
+	for i := len(l) - 1; i >= 0; i-- {
+		b := l[i]
+		if price.GreaterThan(b.From) && price.LessThanOrEqual(b.To) {
+			steps := price.Sub(b.From).Div(b.Tick).Ceil().Sub(decimal.NewFromInt(1))
+			return b.From.Add(steps.Mul(b.Tick)), nil
+		}
+	}
+	return decimal.Zero, ErrPriceNotOnLadder
+}
@@ -0,0 +1,112 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLadderValid(t *testing.T) {
+	for _, p := range []string{"1.01", "1.99", "2", "2.02", "3.05", "4.1", "9.8", "19.5", "29", "48", "95", "1000"} {
+		assert.True(t, DefaultLadder.Valid(decimal.RequireFromString(p)), p)
+	}
+	for _, p := range []string{"1", "1.005", "1.9537", "2.01", "3.01", "4.15", "21.5", "97", "1010", "5000"} {
+		assert.False(t, DefaultLadder.Valid(decimal.RequireFromString(p)), p)
+	}
+}
+
+func TestLadderNextTickUp(t *testing.T) {
+	cases := map[string]string{
+		"1":      "1.01",
+		"1.01":   "1.02",
+		"1.99":   "2",
+		"2":      "2.02",
+		"2.01":   "2.02",
+		"3.98":   "4",
+		"9.8":    "10",
+		"1.9537": "1.96",
+		"990":    "1000",
+	}
+	for price, next := range cases {
+		p, err := DefaultLadder.NextTickUp(decimal.RequireFromString(price))
+		assert.Nil(t, err)
+		assert.True(t, p.Equal(decimal.RequireFromString(next)), "%s: %s", price, p)
+	}
+
+	_, err := DefaultLadder.NextTickUp(decimal.NewFromInt(1000))
+	assert.Equal(t, ErrPriceNotOnLadder, err)
+}
+
+func TestLadderNextTickDown(t *testing.T) {
+	cases := map[string]string{
+		"5000":   "1000",
+		"1000":   "990",
+		"2":      "1.99",
+		"2.02":   "2",
+		"2.01":   "2",
+		"4":      "3.95",
+		"1.9537": "1.95",
+		"1.02":   "1.01",
+	}
+	for price, prev := range cases {
+		p, err := DefaultLadder.NextTickDown(decimal.RequireFromString(price))
+		assert.Nil(t, err)
+		assert.True(t, p.Equal(decimal.RequireFromString(prev)), "%s: %s", price, p)
+	}
+
+	_, err := DefaultLadder.NextTickDown(decimal.RequireFromString("1.01"))
+	assert.Equal(t, ErrPriceNotOnLadder, err)
+}
+
+func TestNewLadder(t *testing.T) {
+	l, err := NewLadder(band("1.1", "2", "0.1"), band("2", "5", "0.5"))
+	assert.Nil(t, err)
+	assert.True(t, l.Min().Equal(decimal.RequireFromString("1.1")))
+	assert.True(t, l.Max().Equal(decimal.NewFromInt(5)))
+	assert.True(t, l.Valid(decimal.RequireFromString("3.5")))
+	assert.False(t, l.Valid(decimal.RequireFromString("1.15")))
+
+	_, err = NewLadder()
+	assert.Equal(t, ErrInvalidLadder, err)
+	_, err = NewLadder(band("1", "2", "0.1"))
+	assert.Equal(t, ErrInvalidLadder, err)
+	_, err = NewLadder(band("1.1", "2", "0.4"))
+	assert.Equal(t, ErrInvalidLadder, err)
+	_, err = NewLadder(band("1.1", "2", "0.1"), band("3", "5", "0.5"))
+	assert.Equal(t, ErrInvalidLadder, err)
+	_, err = NewLadder(band("1.1", "2", "0"))
+	assert.Equal(t, ErrInvalidLadder, err)
+}
+
+func TestNewOrderPriceNotOnLadder(t *testing.T) {
+	stake := decimal.NewFromFloat(100.0)
+
+	for _, p := range []string{"1.9537", "5000"} {
+		o, err := NewOrder(Back, decimal.RequireFromString(p), stake)
+
+		assert.Nil(t, o)
+		assert.Equal(t, ErrPriceNotOnLadder, err)
+	}
+}
+
+func TestLadderFloorCeil(t *testing.T) {
+	cases := []struct {
+		price, floor, ceil Price // zero when there is no such price
+	}{
+		{250, 250, 250},
+		{251, 250, 252},
+		{399, 395, 400},
+		{100, 0, 101},
+		{150000, 100000, 0},
+	}
+	for _, c := range cases {
+		floor, ok := DefaultLadder.floor(c.price)
+		assert.Equal(t, c.floor != 0, ok, c.price)
+		assert.Equal(t, c.floor, floor, c.price)
+
+		ceil, ok := DefaultLadder.ceil(c.price)
+		assert.Equal(t, c.ceil != 0, ok, c.price)
+		assert.Equal(t, c.ceil, ceil, c.price)
+	}
+}
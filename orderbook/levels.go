@@ -0,0 +1,84 @@
+package orderbook
+
+import (
+	"container/heap"
+)
+
+// levels - price levels of one side of the book. Map is used for lookup by
+// price and binary heap keeps the best level on top, so insert and removal
+// of a level cost O(log n). Both work with integer prices, so that neither
+// of them allocates
+type levels struct {
+	side   Side
+	limits map[Price]*Limit
+	heap   []*Limit
+}
+
+func newLevels(side Side) *levels {
+	return &levels{
+		side:   side,
+		limits: make(map[Price]*Limit),
+	}
+}
+
+// heap.Interface, best back is the lowest price and best lay is the highest
+func (lv *levels) Len() int { return len(lv.heap) }
+
+func (lv *levels) Less(i, j int) bool {
+	if lv.side == Back {
+		return lv.heap[i].Price < lv.heap[j].Price
+	}
+	return lv.heap[i].Price > lv.heap[j].Price
+}
+
+func (lv *levels) Swap(i, j int) {
+	lv.heap[i], lv.heap[j] = lv.heap[j], lv.heap[i]
+	lv.heap[i].index = i
+	lv.heap[j].index = j
+}
+
+func (lv *levels) Push(x any) {
+	l := x.(*Limit)
+	l.index = len(lv.heap)
+	lv.heap = append(lv.heap, l)
+}
+
+func (lv *levels) Pop() any {
+	n := len(lv.heap) - 1
+	l := lv.heap[n]
+	lv.heap[n] = nil
+	lv.heap = lv.heap[:n]
+	l.index = -1
+	return l
+}
+
+// get - returns level by price or nil
+func (lv *levels) get(price Price) *Limit {
+	return lv.limits[price]
+}
+
+func (lv *levels) add(l *Limit) {
+	lv.limits[l.Price] = l
+	heap.Push(lv, l)
+}
+
+func (lv *levels) remove(l *Limit) {
+	delete(lv.limits, l.Price)
+	heap.Remove(lv, l.index)
+}
+
+// best - returns best price level or nil if side is empty
+func (lv *levels) best() *Limit {
+	if len(lv.heap) == 0 {
+		return nil
+	}
+	return lv.heap[0]
+}
+
+// bestPrice - returns best price or zero if side is empty
+func (lv *levels) bestPrice() Price {
+	if len(lv.heap) == 0 {
+		return 0
+	}
+	return lv.heap[0].Price
+}
@@ -0,0 +1,70 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelsBest(t *testing.T) {
+	back := newLevels(Back)
+	lay := newLevels(Lay)
+	assert.Nil(t, back.best())
+	assert.Zero(t, lay.bestPrice())
+
+	for _, p := range []float64{2.5, 1.5, 3.0, 2.0} {
+		for _, lv := range []*levels{back, lay} {
+			l, err := NewLimit(testPrice(p))
+			assert.Nil(t, err)
+			lv.add(l)
+		}
+	}
+
+	assert.Equal(t, testPrice(1.5), back.bestPrice())
+	assert.Equal(t, testPrice(3.0), lay.bestPrice())
+
+	back.remove(back.get(testPrice(1.5)))
+	lay.remove(lay.get(testPrice(2.0)))
+
+	assert.Equal(t, testPrice(2.0), back.bestPrice())
+	assert.Equal(t, testPrice(3.0), lay.bestPrice())
+	assert.Nil(t, back.get(testPrice(1.5)))
+	assert.Equal(t, 3, back.Len())
+
+	lay.remove(lay.best())
+	assert.Equal(t, testPrice(2.5), lay.bestPrice())
+}
+
+func TestOrderbookBestPrices(t *testing.T) {
+	ob := NewOrderbook()
+	assert.Zero(t, ob.BestBack())
+	assert.Zero(t, ob.BestLay())
+
+	lay := newTestOrder(t, Lay, 1.8, 10.0)
+	back := newTestOrder(t, Back, 2.2, 10.0)
+	for _, o := range []*Order{lay, back} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, back.Price, ob.BestBack())
+	assert.Equal(t, lay.Price, ob.BestLay())
+
+	_, err := ob.CancelOrder(lay.Id)
+	assert.Nil(t, err)
+	assert.Zero(t, ob.BestLay())
+}
+
+func TestOrderbookSameLevelForEqualPrices(t *testing.T) {
+	ob := NewOrderbook()
+	for _, p := range []string{"2", "2.0", "2.00"} {
+		o, err := NewOrder(Back, decimal.RequireFromString(p), decimal.NewFromInt(10))
+		assert.Nil(t, err)
+		_, _, err = ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, 1, ob.backLevels.Len())
+	assert.Equal(t, testAmount(30), ob.backLevels.get(testPrice(2)).TotalVolume)
+}
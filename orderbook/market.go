@@ -0,0 +1,359 @@
+package orderbook
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// MarketStatus - trading state of the market
+type MarketStatus int
+
+const (
+	Open      MarketStatus = iota // trading before the off
+	Suspended                     // no orders accepted until reopened
+	InPlay                        // trading after the off
+	Closed                        // trading is over
+	Settled                       // result is known
+)
+
+func (s MarketStatus) String() string {
+	return [...]string{"Open", "Suspended", "InPlay", "Closed", "Settled"}[s]
+}
+
+// Market - betting market with one Orderbook per selection (runner).
+// Trades of all selections share one sequence
+type Market struct {
+	Id       string
+	Funds    FundsChecker  // optional check of owners' exposure on every order
+	BetDelay time.Duration // in-play orders are held this long before matching
+
+	status      MarketStatus
+	resume      MarketStatus // status restored when suspended market reopens
+	suspensions uint64       // number of times the market was suspended
+	selections  []string
+	books       map[string]*Orderbook
+	accounts    map[string]*account
+	spBets      map[string][]*SPBet
+	held        []*held
+	clock       func() time.Time
+	seq         uint64
+	entry       uint64 // journal sequence of the last applied command
+}
+
+func NewMarket(id string, selections ...string) (*Market, error) {
+	m := &Market{
+		Id:       id,
+		status:   Open,
+		books:    make(map[string]*Orderbook),
+		accounts: make(map[string]*account),
+		spBets:   make(map[string][]*SPBet),
+		clock:    time.Now,
+	}
+
+	for _, s := range selections {
+		if _, ok := m.books[s]; ok {
+			return nil, ErrSelectionExists
+		}
+		ob := newOrderbook(&m.seq)
+		ob.selection = s
+		ob.cross = &crossMatcher{market: m, selection: s}
+		ob.filled = m.fill(s)
+		ob.cancelled = m.cancel(s)
+		m.books[s] = ob
+		m.selections = append(m.selections, s)
+	}
+	return m, nil
+}
+
+func (m *Market) Status() MarketStatus {
+	return m.status
+}
+
+// Selections - returns selection ids in the order they were added
+func (m *Market) Selections() []string {
+	return append([]string(nil), m.selections...)
+}
+
+// Book - returns orderbook of the selection
+func (m *Market) Book(selection string) (*Orderbook, error) {
+	ob, ok := m.books[selection]
+	if !ok {
+		return nil, ErrSelectionNotFound
+	}
+	return ob, nil
+}
+
+// Depth - returns n best price levels of the selection including virtual
+// liquidity cross-matched from other selections. Orders count only resting
+// orders of the selection
+func (m *Market) Depth(selection string, n int) (Depth, error) {
+	ob, err := m.Book(selection)
+	if err != nil {
+		return Depth{}, err
+	}
+
+	d := ob.FullDepth()
+	if price, volume, ok := ob.cross.best(Back); ok {
+		d.Lay = mergeLevel(d.Lay, Level{Price: price, Volume: volume}, Back)
+	}
+	if price, volume, ok := ob.cross.best(Lay); ok {
+		d.Back = mergeLevel(d.Back, Level{Price: price, Volume: volume}, Lay)
+	}
+
+	if n > 0 && n < len(d.Back) {
+		d.Back = d.Back[:n]
+	}
+	if n > 0 && n < len(d.Lay) {
+		d.Lay = d.Lay[:n]
+	}
+	return d, nil
+}
+
+// find - returns orderbook where order with given id rests
+func (m *Market) find(id string) (*Orderbook, error) {
+	for _, s := range m.selections {
+		if _, ok := m.books[s].orders[id]; ok {
+			return m.books[s], nil
+		}
+	}
+	return nil, ErrOrderNotFound
+}
+
+// trading - checks if the market accepts new orders
+func (m *Market) trading() error {
+	switch m.status {
+	case Suspended:
+		return ErrMarketSuspended
+	case Closed, Settled:
+		return ErrMarketClosed
+	}
+	return nil
+}
+
+// AddOrder - routes order to the orderbook of the selection. In-play orders
+// of the market with bet delay are held and left Pending, see Release
+func (m *Market) AddOrder(selection string, o *Order) (trades []Trade, partial *Order, err error) {
+	if err := m.trading(); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := m.Book(selection); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := m.find(o.Id); err == nil || m.holds(o.Id) {
+		return nil, nil, ErrOrderExists
+	}
+
+	if err := m.checkFunds(o.Owner, orderRisk(selection, o)); err != nil {
+		return nil, nil, err
+	}
+
+	if m.status == InPlay && m.BetDelay > 0 {
+		m.hold(selection, o)
+		return
+	}
+	return m.place(selection, o)
+}
+
+// place - matches order in the orderbook of the selection and records its
+// unmatched remainder in owner's account
+func (m *Market) place(selection string, o *Order) (trades []Trade, partial *Order, err error) {
+	ob := m.books[selection]
+	trades, partial, err = ob.AddOrder(o)
+	if err != nil {
+		return
+	}
+
+	if _, ok := ob.orders[o.Id]; ok {
+		m.account(o.Owner).rest(selection, o)
+	}
+	return
+}
+
+// CancelOrder - cancels resting order in any selection of the market
+func (m *Market) CancelOrder(id string) (*Order, error) {
+	ob, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := ob.CancelOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	m.account(o.Owner).remove(ob.selection, o)
+	return o, nil
+}
+
+// ReduceOrder - reduces stake of resting order in any selection of the market
+func (m *Market) ReduceOrder(id string, amount decimal.Decimal) (*Order, error) {
+	ob, err := m.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := NewAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := ob.ReduceOrder(id, a)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ob.orders[id]; !ok {
+		m.account(o.Owner).remove(ob.selection, o)
+	}
+	return o, nil
+}
+
+// AmendOrder - changes price and stake of resting order in any selection of
+// the market, see Orderbook.AmendOrder. In-play change of price with bet
+// delay takes the order out of DOM and holds it Pending, see Release
+func (m *Market) AmendOrder(id string, price, stake decimal.Decimal) (trades []Trade, o *Order, err error) {
+	if err := m.trading(); err != nil {
+		return nil, nil, err
+	}
+
+	ob, err := m.find(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, s, err := convert(price, stake)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o = ob.orders[id].Value.(*Order)
+	a := m.account(o.Owner)
+	a.remove(ob.selection, o)
+
+	amended := *o
+	amended.Price, amended.Stake = p, s
+	if err = m.checkFunds(o.Owner, orderRisk(ob.selection, &amended)); err != nil {
+		a.rest(ob.selection, o)
+		return nil, nil, err
+	}
+
+	// new price in-play may be matched at once, so like a new order it waits
+	// for bet delay and is cancelled if the market is suspended meanwhile
+	if m.status == InPlay && m.BetDelay > 0 && p != o.Price {
+		ob.CancelOrder(id)
+		o.Price, o.Stake = p, s
+		m.hold(ob.selection, o)
+		return nil, o, nil
+	}
+
+	trades, _, err = ob.AmendOrder(id, p, s)
+	if _, ok := ob.orders[id]; ok {
+		a.rest(ob.selection, o)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return
+}
+
+// TurnInPlay - moves open market in-play. Market on close orders are taken
+// out of DOM and matched together with SP bets at the starting price of
+// their selection against unmatched orders of the book. Afterwards lapse
+// orders are cancelled and persist orders stay in DOM. Returns every order
+// whose unmatched part lapsed and reconciliations of all selections
+func (m *Market) TurnInPlay() (lapsed []*Order, sp []Reconciliation, err error) {
+	if m.status != Open {
+		return nil, nil, ErrMarketStatus
+	}
+	m.status = InPlay
+
+	for _, s := range m.selections {
+		ob := m.books[s]
+
+		var converted []*Order
+		for _, side := range []Side{Back, Lay} {
+			for _, o := range ob.Orders(side) {
+				if o.Persistence == MarketOnClose {
+					ob.CancelOrder(o.Id)
+					converted = append(converted, o)
+				}
+			}
+		}
+
+		sp = append(sp, m.reconcile(s, converted))
+		for _, o := range converted {
+			if o.Status == Cancelled {
+				lapsed = append(lapsed, o)
+			}
+		}
+
+		for _, side := range []Side{Back, Lay} {
+			for _, o := range ob.Orders(side) {
+				if o.Persistence == Lapse {
+					ob.CancelOrder(o.Id)
+					m.account(o.Owner).remove(s, o)
+					lapsed = append(lapsed, o)
+				}
+			}
+		}
+	}
+	return
+}
+
+// Suspend - stops accepting orders in open or in-play market, e.g. on a goal.
+// Orders held by bet delay at the moment are rejected on release
+func (m *Market) Suspend() error {
+	if m.status != Open && m.status != InPlay {
+		return ErrMarketStatus
+	}
+	m.resume = m.status
+	m.status = Suspended
+	m.suspensions++
+	return nil
+}
+
+// Reopen - resumes trading in suspended market
+func (m *Market) Reopen() error {
+	if m.status != Suspended {
+		return ErrMarketStatus
+	}
+	m.status = m.resume
+	return nil
+}
+
+// Close - stops trading in the market, unmatched and held orders are
+// cancelled and returned
+func (m *Market) Close() (cancelled []*Order) {
+	if m.status == Settled {
+		return nil
+	}
+	m.status = Closed
+
+	for _, h := range m.held {
+		h.order.Status = Cancelled
+		cancelled = append(cancelled, h.order)
+	}
+	m.held = nil
+
+	for _, s := range m.selections {
+		ob := m.books[s]
+		for _, side := range []Side{Back, Lay} {
+			for _, o := range ob.Orders(side) {
+				ob.CancelOrder(o.Id)
+				m.account(o.Owner).remove(s, o)
+				cancelled = append(cancelled, o)
+			}
+		}
+	}
+	return
+}
+
+// Settle - marks closed market as settled
+func (m *Market) Settle() error {
+	if m.status != Closed {
+		return ErrMarketStatus
+	}
+	m.status = Settled
+	return nil
+}
@@ -0,0 +1,216 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMarket(t *testing.T) {
+	m, err := NewMarket("1", "home", "draw", "away")
+
+	assert.Nil(t, err)
+	assert.Equal(t, Open, m.Status())
+	assert.Equal(t, []string{"home", "draw", "away"}, m.Selections())
+
+	ob, err := m.Book("draw")
+	assert.Nil(t, err)
+	assert.NotNil(t, ob)
+
+	_, err = m.Book("unknown")
+	assert.Equal(t, ErrSelectionNotFound, err)
+
+	_, err = NewMarket("2", "home", "home")
+	assert.Equal(t, ErrSelectionExists, err)
+}
+
+func TestMarketStatusString(t *testing.T) {
+	assert.Equal(t, "Open", Open.String())
+	assert.Equal(t, "Suspended", Suspended.String())
+	assert.Equal(t, "InPlay", InPlay.String())
+	assert.Equal(t, "Closed", Closed.String())
+	assert.Equal(t, "Settled", Settled.String())
+}
+
+func TestMarketSuspendAndReopen(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	o := newTestOrder(t, Back, 3.0, 10.0)
+	_, _, err := m.AddOrder("home", o)
+	assert.Nil(t, err)
+
+	assert.Nil(t, m.Suspend())
+	assert.Equal(t, Suspended, m.Status())
+	assert.Equal(t, ErrMarketStatus, m.Suspend())
+
+	_, _, err = m.AddOrder("home", newTestOrder(t, Back, 3.0, 10.0))
+	assert.Equal(t, ErrMarketSuspended, err)
+	_, _, err = m.AmendOrder(o.Id, o.Price.Decimal(), decimal.NewFromInt(20))
+	assert.Equal(t, ErrMarketSuspended, err)
+	_, err = m.ReduceOrder(o.Id, decimal.NewFromInt(4))
+	assert.Nil(t, err)
+
+	assert.Nil(t, m.Reopen())
+	assert.Equal(t, Open, m.Status())
+	assert.Equal(t, ErrMarketStatus, m.Reopen())
+
+	_, _, err = m.TurnInPlay()
+	assert.Nil(t, err)
+	assert.Nil(t, m.Suspend())
+	assert.Nil(t, m.Reopen())
+	assert.Equal(t, InPlay, m.Status())
+}
+
+func TestMarketSettle(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	assert.Equal(t, ErrMarketStatus, m.Settle())
+
+	m.Close()
+	assert.Equal(t, ErrMarketStatus, m.Suspend())
+	assert.Nil(t, m.Settle())
+	assert.Equal(t, Settled, m.Status())
+
+	assert.Nil(t, m.Close())
+	assert.Equal(t, Settled, m.Status())
+	_, _, err := m.AddOrder("home", newTestOrder(t, Back, 3.0, 10.0))
+	assert.Equal(t, ErrMarketClosed, err)
+}
+
+func TestMarketAddOrderRouting(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	lay := newTestOrder(t, Lay, 2.0, 10.0)
+	back := newTestOrder(t, Back, 2.0, 10.0)
+
+	_, _, err := m.AddOrder("home", lay)
+	assert.Nil(t, err)
+	trades, _, err := m.AddOrder("away", back)
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+
+	home, _ := m.Book("home")
+	away, _ := m.Book("away")
+	assert.Equal(t, lay.Price, home.BestLay())
+	assert.Equal(t, back.Price, away.BestBack())
+
+	_, _, err = m.AddOrder("draw", newTestOrder(t, Back, 2.0, 10.0))
+	assert.Equal(t, ErrSelectionNotFound, err)
+	_, _, err = m.AddOrder("home", back)
+	assert.Equal(t, ErrOrderExists, err)
+}
+
+func TestMarketSharedSequence(t *testing.T) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	for _, s := range []string{"home", "away"} {
+		_, _, err := m.AddOrder(s, newTestOrder(t, Lay, 2.0, 10.0))
+		assert.Nil(t, err)
+	}
+
+	t1, _, _ := m.AddOrder("home", newTestOrder(t, Back, 2.0, 10.0))
+	t2, _, _ := m.AddOrder("away", newTestOrder(t, Back, 2.0, 10.0))
+
+	assert.Equal(t, uint64(1), t1[0].Seq)
+	assert.Equal(t, uint64(2), t2[0].Seq)
+}
+
+func TestMarketCancelAndReduce(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	o := newTestOrder(t, Back, 3.0, 10.0)
+	_, _, err := m.AddOrder("away", o)
+	assert.Nil(t, err)
+
+	reduced, err := m.ReduceOrder(o.Id, decimal.NewFromFloat(4.0))
+	assert.Nil(t, err)
+	assert.Equal(t, testAmount(6.0), reduced.Stake)
+
+	cancelled, err := m.CancelOrder(o.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, o, cancelled)
+
+	_, err = m.CancelOrder(o.Id)
+	assert.Equal(t, ErrOrderNotFound, err)
+}
+
+func TestMarketAmendOrder(t *testing.T) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	m.Funds = testFunds{"alice": decimal.NewFromInt(20), "bob": decimal.NewFromInt(100)}
+	lay := newOwnedOrder(t, "bob", Lay, 2.0, 10.0)
+	back := newOwnedOrder(t, "alice", Back, 3.0, 10.0)
+	for _, o := range []*Order{lay, back} {
+		_, _, err := m.AddOrder("home", o)
+		assert.Nil(t, err)
+	}
+
+	_, _, err := m.AmendOrder(back.Id, back.Price.Decimal(), decimal.NewFromInt(25))
+	assert.Equal(t, ErrInsufficientFunds, err)
+	assert.Equal(t, testAmount(10), back.Stake)
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+
+	trades, o, err := m.AmendOrder(back.Id, decimal.NewFromFloat(2.0), decimal.NewFromInt(15))
+
+	assert.Nil(t, err)
+	assert.Equal(t, back, o)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, testAmount(5), back.Stake)
+	assert.Equal(t, map[string]*Order{back.Id: back}, m.account("alice").orders["home"])
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(15)))
+
+	_, _, err = m.AmendOrder(lay.Id, lay.Price.Decimal(), lay.Stake.Decimal())
+	assert.Equal(t, ErrOrderNotFound, err)
+}
+
+func TestMarketClose(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	orders := []*Order{
+		newTestOrder(t, Back, 3.0, 10.0),
+		newTestOrder(t, Lay, 2.0, 10.0),
+	}
+	for _, o := range orders {
+		_, _, err := m.AddOrder("home", o)
+		assert.Nil(t, err)
+	}
+
+	cancelled := m.Close()
+
+	assert.Equal(t, Closed, m.Status())
+	assert.ElementsMatch(t, orders, cancelled)
+	home, _ := m.Book("home")
+	assert.Empty(t, home.orders)
+
+	_, _, err := m.AddOrder("home", newTestOrder(t, Back, 3.0, 10.0))
+	assert.Equal(t, ErrMarketClosed, err)
+}
+
+func TestMarketTurnInPlay(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	lapse := newOwnedOrder(t, "alice", Back, 3.0, 10.0)
+	persist := newOwnedOrder(t, "alice", Lay, 2.0, 10.0)
+	persist.Persistence = Persist
+	moc := newOwnedOrder(t, "alice", Back, 4.0, 10.0)
+	moc.Persistence = MarketOnClose
+	for _, o := range []*Order{lapse, persist, moc} {
+		_, _, err := m.AddOrder("home", o)
+		assert.Nil(t, err)
+	}
+
+	lapsed, sp, err := m.TurnInPlay()
+
+	assert.Nil(t, err)
+	assert.Equal(t, InPlay, m.Status())
+	assert.Equal(t, []*Order{moc, lapse}, lapsed)
+	assert.Equal(t, Cancelled, lapse.Status)
+	assert.Equal(t, Cancelled, moc.Status)
+	assert.Equal(t, Resting, persist.Status)
+	assert.Len(t, sp, 2)
+	assert.True(t, sp[0].Price.IsZero())
+
+	home, _ := m.Book("home")
+	assert.Equal(t, []*Order{persist}, home.Orders(Lay))
+	assert.Empty(t, home.Orders(Back))
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+
+	_, _, err = m.AddOrder("away", newTestOrder(t, Back, 3.0, 10.0))
+	assert.Nil(t, err)
+
+	_, _, err = m.TurnInPlay()
+	assert.Equal(t, ErrMarketStatus, err)
+}
@@ -4,18 +4,33 @@ import (
 	"container/list"
 	"errors"
 	"fmt"
+	"strconv"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
 var (
 	ErrInvalidStake      = errors.New("orderbook: invalid order stake")
+	ErrInvalidPeak       = errors.New("orderbook: invalid iceberg peak")
 	ErrInvalidOrderPrice = errors.New("orderbook: invalid order price")
+	ErrPriceNotOnLadder  = errors.New("orderbook: price is not on the ladder")
+	ErrInvalidLadder     = errors.New("orderbook: invalid price ladder")
 	ErrInvalidLimitPrice = errors.New("orderbook: invalid limit price")
 	ErrPriceMismatch     = errors.New("orderbook: limit and order price mismatch")
 	ErrOrderExists       = errors.New("orderbook: order id already exists")
+	ErrOrderNotFound     = errors.New("orderbook: order not found")
+	ErrMarketClosed      = errors.New("orderbook: market is closed")
+	ErrMarketSuspended   = errors.New("orderbook: market is suspended")
+	ErrMarketStatus      = errors.New("orderbook: invalid market status transition")
+	ErrSelectionNotFound = errors.New("orderbook: selection not found")
+	ErrSelectionExists   = errors.New("orderbook: selection already exists")
+	ErrInsufficientFunds = errors.New("orderbook: insufficient funds")
+	ErrUnknownCommand    = errors.New("orderbook: unknown command")
+	ErrSequencerStopped  = errors.New("orderbook: sequencer is stopped")
+	ErrJournalGap        = errors.New("orderbook: journal entries are not consecutive")
+	ErrNoSnapshot        = errors.New("orderbook: journal has no snapshot")
 )
 
 // Side represents type of the order Back or Lay
@@ -30,147 +45,628 @@ func (s Side) String() string {
 	return [...]string{"Back", "Lay"}[s]
 }
 
+func (s Side) Opposite() Side {
+	if s == Back {
+		return Lay
+	}
+	return Back
+}
+
+// better - checks if price a is better than price b for the taker of the side
+func (s Side) better(a, b Price) bool {
+	if s == Back {
+		return a > b
+	}
+	return a < b
+}
+
+// TimeInForce - how long unmatched part of the order stays in DOM
+type TimeInForce int
+
+const (
+	GoodTilCancel     TimeInForce = iota // rests until matched or cancelled
+	ImmediateOrCancel                    // unmatched part is cancelled at once
+	FillOrKill                           // matched completely or not at all
+)
+
+func (t TimeInForce) String() string {
+	return [...]string{"GTC", "IOC", "FOK"}[t]
+}
+
+// Persistence - what happens to unmatched part of the order when market
+// turns in-play
+type Persistence int
+
+const (
+	Lapse         Persistence = iota // cancelled
+	Persist                          // stays in DOM
+	MarketOnClose                    // converted to starting price bet
+)
+
+func (p Persistence) String() string {
+	return [...]string{"Lapse", "Persist", "MarketOnClose"}[p]
+}
+
+// OrderStatus - state of the order after it was processed by the book
+type OrderStatus int
+
+const (
+	Pending   OrderStatus = iota // not added to the book yet
+	Resting                      // unmatched part rests in DOM
+	Filled                       // completely matched
+	Cancelled                    // unmatched part cancelled
+	Killed                       // fill or kill order that could not be filled
+)
+
+func (s OrderStatus) String() string {
+	return [...]string{"Pending", "Resting", "Filled", "Cancelled", "Killed"}[s]
+}
+
 // Order
-var minPrice = decimal.NewFromFloat(1.0)
+var minPrice = priceOne.Decimal()
 
 type Order struct {
-	Side      Side
-	Id        string
-	Price     decimal.Decimal
-	Stake     decimal.Decimal
-	CreatedAt int64
+	Side      Side   `json:"side"`
+	Id        string `json:"id"`
+	Owner     string `json:"owner"` // account the order belongs to
+	Price     Price  `json:"price"`
+	Stake     Amount `json:"stake"` // unmatched stake
+	Peak      Amount `json:"peak"`  // displayed part of iceberg order, zero for plain order
+	CreatedAt int64  `json:"created_at"`
+
+	TimeInForce TimeInForce         `json:"time_in_force"`
+	Persistence Persistence         `json:"persistence"`
+	SelfTrade   SelfTradePrevention `json:"self_trade"`
+	Status      OrderStatus         `json:"status"`
+
+	limit *Limit // price level the order rests at
+	shown Amount // displayed part of resting iceberg order
 }
 
 func (o Order) String() string {
 	return fmt.Sprintf("[Id: %s, Side: %s, Stake: %s, Price: %s]", o.Id, o.Side, o.Stake, o.Price)
 }
 
-func NewOrder(side Side, price, stake decimal.Decimal) (*Order, error) {
-	if stake.Sign() <= 0 {
-		return nil, ErrInvalidStake
+// accepts - checks if order can be matched at price, backs take prices at
+// or above order price and lays take prices at or below it
+func (o *Order) accepts(price Price) bool {
+	if o.Side == Back {
+		return price >= o.Price
 	}
+	return price <= o.Price
+}
 
-	if price.LessThanOrEqual(minPrice) {
-		return nil, ErrInvalidOrderPrice
+// validate - checks stake and price of the order
+func validate(price Price, stake Amount) error {
+	if stake <= 0 {
+		return ErrInvalidStake
+	}
+
+	if price <= priceOne {
+		return ErrInvalidOrderPrice
+	}
+
+	if !DefaultLadder.valid(price) {
+		return ErrPriceNotOnLadder
+	}
+	return nil
+}
+
+// convert - converts decimal price and stake to the representation of the
+// engine and validates them. Stakes finer than a minor unit are invalid and
+// prices finer than a hundredth are never on the ladder
+func convert(price, stake decimal.Decimal) (Price, Amount, error) {
+	a, err := NewAmount(stake)
+	if err != nil {
+		return 0, 0, err
+	}
+	p, err := NewPrice(price)
+	switch {
+	case err == nil:
+		return p, a, validate(p, a)
+	case a <= 0:
+		return 0, 0, ErrInvalidStake
+	case price.GreaterThan(minPrice):
+		return 0, 0, ErrPriceNotOnLadder
+	}
+	return 0, 0, err
+}
+
+// lastId - source of ids, seeded with start time so that ids stay unique
+// across restarts
+var lastId = uint64(time.Now().UnixNano())
+
+// nextId - returns new unique id, much cheaper than a random uuid
+func nextId() string {
+	return strconv.FormatUint(atomic.AddUint64(&lastId, 1), 36)
+}
+
+func NewOrder(side Side, price, stake decimal.Decimal) (*Order, error) {
+	p, a, err := convert(price, stake)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Order{
-		Id:        uuid.New().String(), // TODO: replace with actual id
+		Id:        nextId(),
 		Side:      side,
-		Price:     price,
-		Stake:     stake,
+		Price:     p,
+		Stake:     a,
 		CreatedAt: time.Now().UnixNano(),
 	}, nil
 }
 
 // Limit - price level in DOM
 type Limit struct {
-	Price       decimal.Decimal
-	TotalVolume decimal.Decimal
+	Price       Price
+	TotalVolume Amount // displayed stake, hidden reserve of icebergs excluded
 	Orders      *list.List
+
+	index int // position in levels heap
 }
 
 func (l Limit) String() string {
 	return fmt.Sprintf("[Price: %s, TotalVolume: %s, Len: %d]", l.Price, l.TotalVolume, l.Orders.Len())
 }
 
-func NewLimit(price decimal.Decimal) (*Limit, error) {
-	if price.LessThanOrEqual(minPrice) {
+func NewLimit(price Price) (*Limit, error) {
+	if price <= priceOne {
 		return nil, ErrInvalidLimitPrice
 	}
 
 	return &Limit{
-		Price:       price,
-		TotalVolume: decimal.Zero,
-		Orders:      list.New(),
+		Price:  price,
+		Orders: list.New(),
 	}, nil
 }
 
 func (l *Limit) AddOrder(o *Order) (*list.Element, error) {
-	if !l.Price.Equal(o.Price) {
+	if l.Price != o.Price {
 		return nil, ErrPriceMismatch
 	}
 
-	l.TotalVolume = l.TotalVolume.Add(o.Stake)
+	o.limit = l
+	if o.iceberg() {
+		o.shown = minAmount(o.Peak, o.Stake)
+	}
+	l.TotalVolume += o.visible()
 	return l.Orders.PushBack(o), nil
 }
 
 func (l *Limit) RemoveOrder(e *list.Element) *Order {
-	l.TotalVolume = l.TotalVolume.Sub(e.Value.(*Order).Stake)
+	o := e.Value.(*Order)
+	l.TotalVolume -= o.visible()
+	o.limit = nil
 	return l.Orders.Remove(e).(*Order)
 }
 
-// Orderbook
+// reduce - decreases unmatched stake of the order resting at the level,
+// hidden reserve of iceberg order is used up first
+func (l *Limit) reduce(o *Order, amount Amount) {
+	visible := o.visible()
+	o.Stake -= amount
+	if o.iceberg() {
+		o.shown = minAmount(o.shown, o.Stake)
+	}
+	l.TotalVolume -= visible - o.visible()
+}
+
+// Trade - single match between resting maker order and incoming taker order.
+// Taker leg of cross-matched order has empty MakerId, its counterparts are
+// reported as separate trades on other selections of the market
+type Trade struct {
+	Seq       uint64
+	Selection string
+	MakerId   string
+	TakerId   string
+	Side      Side // side of the taker
+	Price     Price
+	Stake     Amount
+}
+
+func (t Trade) String() string {
+	return fmt.Sprintf("[Seq: %d, Maker: %s, Taker: %s, Side: %s, Stake: %s, Price: %s]", t.Seq, t.MakerId, t.TakerId, t.Side, t.Stake, t.Price)
+}
+
+// Orderbook - two-sided book of a single selection. Unmatched backs rest
+// above unmatched lays: a Back order crosses resting lays priced at or above
+// it, a Lay order crosses resting backs priced at or below it.
 type Orderbook struct {
 	orders map[string]*list.Element // from Limit.Orders list
 
-	backLevels map[string]*Limit
-	layLevels  map[string]*Limit
+	backLevels *levels
+	layLevels  *levels
+
+	seq *uint64 // sequence number of the last trade, shared within market
+
+	selection string
+	cross     virtualLiquidity // set for books of multi-selection market
 
-	backBest decimal.Decimal
-	layBest  decimal.Decimal
+	filled    func(o *Order, price Price, stake Amount) // called on every matched part of an order
+	cancelled func(o *Order)                            // called when self-trade prevention cancels resting order
 }
 
 func NewOrderbook() *Orderbook {
+	return newOrderbook(new(uint64))
+}
+
+func newOrderbook(seq *uint64) *Orderbook {
 	return &Orderbook{
 		orders:     map[string]*list.Element{},
-		backLevels: make(map[string]*Limit),
-		layLevels:  make(map[string]*Limit),
-		backBest:   decimal.Zero,
-		layBest:    decimal.Zero,
+		backLevels: newLevels(Back),
+		layLevels:  newLevels(Lay),
+		seq:        seq,
+	}
+}
+
+// BestBack - lowest resting back price, zero when there are no backs
+func (ob *Orderbook) BestBack() Price {
+	return ob.backLevels.bestPrice()
+}
+
+// BestLay - highest resting lay price, zero when there are no lays
+func (ob *Orderbook) BestLay() Price {
+	return ob.layLevels.bestPrice()
+}
+
+// sideLevels - returns price levels of the side
+func (ob *Orderbook) sideLevels(side Side) *levels {
+	if side == Back {
+		return ob.backLevels
 	}
+	return ob.layLevels
 }
 
 // PlaceOrder place order in DOM without filling
-func (ob *Orderbook) PlaceOrder(o *Order, limits map[string]*Limit) (e *list.Element, err error) {
-	strPrice := o.Price.String()
-	limit, ok := limits[strPrice]
+func (ob *Orderbook) PlaceOrder(o *Order) (e *list.Element, err error) {
+	limits := ob.sideLevels(o.Side)
+	limit := limits.get(o.Price)
 
-	if !ok {
+	if limit == nil {
 		limit, err = NewLimit(o.Price)
 		if err != nil {
 			return
 		}
-		limits[strPrice] = limit
+		limits.add(limit)
 	}
 
 	e, err = limit.AddOrder(o)
 	return
 }
 
-// FillOrder - filling order by removing liquidity from market
-func (ob *Orderbook) FillOrder(o *Order) (*list.Element, error) {
-	return nil, nil
+// crosses - checks if order can be matched against opposite side
+func (ob *Orderbook) crosses(o *Order) bool {
+	if l := ob.sideLevels(o.Side.Opposite()).best(); l != nil && o.accepts(l.Price) {
+		return true
+	}
+	_, _, ok := ob.virtual(o)
+	return ok
 }
 
-func (ob *Orderbook) AddOrder(o *Order) (partial *Order, err error) {
-	if _, ok := ob.orders[o.Id]; ok {
-		return nil, ErrOrderExists
+// virtual - returns virtual level the order can be matched at
+func (ob *Orderbook) virtual(o *Order) (price Price, volume Amount, ok bool) {
+	if ob.cross == nil {
+		return
+	}
+	price, volume, ok = ob.cross.best(o.Side)
+	if !ok || !o.accepts(price) {
+		return 0, 0, false
 	}
+	return
+}
 
-	var e *list.Element
+// match - consumes up to stake from the price level in time priority on
+// behalf of the taker, drops filled makers and the level once it is empty.
+// Iceberg makers are matched for their displayed part, then replenished and
+// moved to the back of the level. Matching stops before the first maker of
+// the owner, which is returned as self, owner is empty when self-trades need
+// not be checked
+func (ob *Orderbook) match(limit *Limit, takerId, owner string, side Side, stake Amount) (trades []Trade, matched Amount, self *Order) {
+	for e := limit.Orders.Front(); e != nil && matched < stake; {
+		next := e.Next()
+		maker := e.Value.(*Order)
+		if owner != "" && maker.Owner == owner {
+			return trades, matched, maker
+		}
 
-	if o.Side == Back {
-		if ob.layBest == decimal.Zero || o.Price.LessThan(ob.layBest) {
-			e, err = ob.PlaceOrder(o, ob.backLevels)
-			if err != nil {
-				return
+		fill := minAmount(stake-matched, maker.visible())
+		matched += fill
+		maker.Stake -= fill
+		limit.TotalVolume -= fill
+		if maker.iceberg() {
+			maker.shown -= fill
+		}
+
+		*ob.seq++
+		trades = append(trades, Trade{
+			Seq:       *ob.seq,
+			Selection: ob.selection,
+			MakerId:   maker.Id,
+			TakerId:   takerId,
+			Side:      side,
+			Price:     limit.Price,
+			Stake:     fill,
+		})
+
+		if ob.filled != nil {
+			ob.filled(maker, limit.Price, fill)
+		}
+
+		if maker.Stake == 0 {
+			maker.Status = Filled
+			limit.RemoveOrder(e)
+			delete(ob.orders, maker.Id)
+		} else if maker.iceberg() && maker.shown == 0 {
+			limit.replenish(e)
+			if next == nil {
+				next = e
 			}
-		} else {
-			ob.FillOrder(o)
-		}
-	} else {
-		if ob.backBest == decimal.Zero || o.Price.GreaterThan(ob.backBest) {
-			e, err = ob.PlaceOrder(o, ob.layLevels)
-			if err != nil {
-				return
+		}
+		e = next
+	}
+
+	if limit.Orders.Len() == 0 {
+		ob.sideLevels(side.Opposite()).remove(limit)
+	}
+	return
+}
+
+// FillOrder - filling order by removing liquidity from market. Opposite side
+// is consumed best price first and in time priority within a price level,
+// virtual liquidity of the market is taken when its price is strictly better.
+// Resting orders of the same owner are handled by order's SelfTrade policy,
+// when the policy stops the order its remainder is Cancelled. Returns trades
+// in matching order and unmatched remainder of the order or nil if it was
+// completely filled
+func (ob *Orderbook) FillOrder(o *Order) (trades []Trade, partial *Order) {
+	stopped := false
+	for !stopped && o.Stake > 0 {
+		limit := ob.sideLevels(o.Side.Opposite()).best()
+		if limit != nil && !o.accepts(limit.Price) {
+			limit = nil
+		}
+
+		if price, volume, ok := ob.virtual(o); ok && (limit == nil || o.Side.better(price, limit.Price)) {
+			stake := minAmount(o.Stake, volume)
+			trades = append(trades, ob.cross.fill(o, price, stake)...)
+			o.Stake -= stake
+
+			if ob.filled != nil {
+				ob.filled(o, price, stake)
 			}
-		} else {
-			ob.FillOrder(o)
+			continue
 		}
+
+		if limit == nil {
+			break
+		}
+
+		t, matched, self := ob.match(limit, o.Id, o.Owner, o.Side, o.Stake)
+		trades = append(trades, t...)
+		o.Stake -= matched
+
+		if ob.filled != nil {
+			for _, tr := range t {
+				ob.filled(o, tr.Price, tr.Stake)
+			}
+		}
+
+		if self != nil {
+			stopped = !ob.preventSelfTrade(o, self)
+		}
+	}
+
+	if o.Stake > 0 {
+		partial = o
+	}
+	switch {
+	case stopped:
+		o.Status = Cancelled
+	case partial == nil:
+		o.Status = Filled
+	}
+	return
+}
+
+// queued - maker as match would reach it, shown is the part it is matched
+// for before it is replenished
+type queued struct {
+	maker        *Order
+	shown, stake Amount
+}
+
+// available - volume the order can be matched with right away. Makers are
+// taken in the order match takes them, replenished icebergs join the back of
+// their level. Resting orders of the same owner are skipped when the order
+// cancels them, otherwise volume behind the first of them is not counted
+func (ob *Orderbook) available(o *Order) Amount {
+	var volume Amount
+	var stop *Limit
+levels:
+	for _, l := range ob.sideLevels(o.Side.Opposite()).sorted() {
+		if !o.accepts(l.Price) {
+			break
+		}
+
+		queue := make([]queued, 0, l.Orders.Len())
+		for e := l.Orders.Front(); e != nil; e = e.Next() {
+			maker := e.Value.(*Order)
+			queue = append(queue, queued{maker: maker, shown: maker.visible(), stake: maker.Stake})
+		}
+		for len(queue) > 0 {
+			q := queue[0]
+			queue = queue[1:]
+			if o.self(q.maker) {
+				if o.SelfTrade != CancelOldest {
+					stop = l
+					break levels
+				}
+				continue
+			}
+
+			volume += q.shown
+			if volume >= o.Stake {
+				return volume
+			}
+			if rest := q.stake - q.shown; rest > 0 {
+				queue = append(queue, queued{maker: q.maker, shown: minAmount(q.maker.Peak, rest), stake: rest})
+			}
+		}
+	}
+
+	if price, v, ok := ob.virtual(o); ok && (stop == nil || o.Side.better(price, stop.Price)) {
+		volume += v
+	}
+	return volume
+}
+
+// AddOrder - matches order against opposite side and places unmatched
+// remainder in DOM according to order time in force. Returns trades
+// generated by the order, partial is returned when order was matched only
+// partially. Outcome of the order is reported by its Status: unmatched
+// remainder of IOC order is Cancelled and FOK order which can not be filled
+// completely is Killed without any trades
+func (ob *Orderbook) AddOrder(o *Order) (trades []Trade, partial *Order, err error) {
+	if _, ok := ob.orders[o.Id]; ok {
+		return nil, nil, ErrOrderExists
+	}
+
+	if o.Peak < 0 {
+		return nil, nil, ErrInvalidPeak
+	}
+
+	if o.TimeInForce == FillOrKill && ob.available(o) < o.Stake {
+		o.Status = Killed
+		return
+	}
+
+	if ob.crosses(o) {
+		trades, partial = ob.FillOrder(o)
+		if partial == nil || o.Status == Cancelled {
+			return
+		}
+	}
+
+	if o.TimeInForce != GoodTilCancel {
+		o.Status = Cancelled
+		return
+	}
+
+	e, err := ob.PlaceOrder(o)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	ob.orders[o.Id] = e
+	o.Status = Resting
+
+	return
+}
+
+// CancelOrder - removes resting order from DOM
+func (ob *Orderbook) CancelOrder(id string) (*Order, error) {
+	e, ok := ob.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+
+	o := e.Value.(*Order)
+	limit := o.limit
+	limit.RemoveOrder(e)
+	delete(ob.orders, id)
+	o.Status = Cancelled
+
+	if limit.Orders.Len() == 0 {
+		ob.sideLevels(o.Side).remove(limit)
+	}
+	return o, nil
+}
+
+// take - matches part of resting order outside of DOM, e.g. at starting price
+func (ob *Orderbook) take(id string, stake Amount) *Order {
+	e, ok := ob.orders[id]
+	if !ok {
+		return nil
+	}
+
+	o := e.Value.(*Order)
+	limit := o.limit
+	limit.reduce(o, stake)
+
+	if o.Stake == 0 {
+		limit.RemoveOrder(e)
+		delete(ob.orders, id)
+		o.Status = Filled
+
+		if limit.Orders.Len() == 0 {
+			ob.sideLevels(o.Side).remove(limit)
+		}
+	}
+	return o
+}
+
+// ReduceOrder - decreases stake of resting order keeping its time priority.
+// Order is cancelled when amount covers its whole remaining stake
+func (ob *Orderbook) ReduceOrder(id string, amount Amount) (*Order, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidStake
+	}
+
+	e, ok := ob.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+
+	o := e.Value.(*Order)
+	if amount >= o.Stake {
+		return ob.CancelOrder(id)
+	}
+
+	o.limit.reduce(o, amount)
+	return o, nil
+}
+
+// AmendOrder - changes price and unmatched stake of resting order. Reducing
+// stake at the same price keeps time priority, increasing it moves the order
+// to the back of its price level. Changing price cancels the order and
+// submits it again at the new price, so it may be matched at once. Returns
+// trades of the resubmitted order and the order in its new state
+func (ob *Orderbook) AmendOrder(id string, price Price, stake Amount) (trades []Trade, o *Order, err error) {
+	if err = validate(price, stake); err != nil {
+		return
+	}
 
-	return nil, nil
+	e, ok := ob.orders[id]
+	if !ok {
+		return nil, nil, ErrOrderNotFound
+	}
+	o = e.Value.(*Order)
+
+	switch {
+	case price != o.Price:
+	case stake < o.Stake:
+		_, err = ob.ReduceOrder(id, o.Stake-stake)
+		return
+	case stake == o.Stake:
+		return
+	}
+
+	ob.CancelOrder(id)
+	o.Price = price
+	o.Stake = stake
+	o.Status = Pending
+
+	trades, _, err = ob.AddOrder(o)
+	return
+}
+
+// Orders - returns resting orders from the best price level in time priority
+func (ob *Orderbook) Orders(side Side) []*Order {
+	var res []*Order
+	for _, l := range ob.sideLevels(side).sorted() {
+		for e := l.Orders.Front(); e != nil; e = e.Next() {
+			res = append(res, e.Value.(*Order))
+		}
+	}
+	return res
 }
@@ -22,8 +22,8 @@ func TestNewOrderBack(t *testing.T) {
 	assert.Equal(t, o.Side, Back)
 	assert.NotEmpty(t, o.Id)
 	assert.LessOrEqual(t, o.CreatedAt, time.Now().UnixNano())
-	assert.Equal(t, o.Stake, stake)
-	assert.Equal(t, o.Price, price)
+	assert.Equal(t, testAmount(100), o.Stake)
+	assert.Equal(t, testPrice(1.95), o.Price)
 }
 
 func TestNewOrderLay(t *testing.T) {
@@ -56,17 +56,17 @@ func TestNewOrderInvalidPrice(t *testing.T) {
 }
 
 func TestNewLimit(t *testing.T) {
-	price := decimal.NewFromFloat(1.95)
+	price := testPrice(1.95)
 	l, err := NewLimit(price)
 
 	assert.Nil(t, err)
 	assert.NotNil(t, l)
-	assert.Equal(t, l.TotalVolume, decimal.Zero)
+	assert.Zero(t, l.TotalVolume)
 	assert.Equal(t, l.Orders.Len(), 0)
 }
 
 func TestNewLimitInvalidPrice(t *testing.T) {
-	price := decimal.NewFromFloat(0.95)
+	price := testPrice(0.95)
 	l, err := NewLimit(price)
 
 	assert.Nil(t, l)
@@ -74,7 +74,7 @@ func TestNewLimitInvalidPrice(t *testing.T) {
 }
 
 func TestAddOrder(t *testing.T) {
-	price := decimal.NewFromFloat(1.95)
+	price := testPrice(1.95)
 	l, _ := NewLimit(price)
 	volumeBefore := l.TotalVolume
 	o := createTestOrder(t)
@@ -83,12 +83,12 @@ func TestAddOrder(t *testing.T) {
 
 	assert.Nil(t, err)
 	assert.Equal(t, e.Value.(*Order), o)
-	assert.True(t, l.TotalVolume.Equal(o.Stake))
-	assert.True(t, l.TotalVolume.GreaterThan(volumeBefore))
+	assert.Equal(t, o.Stake, l.TotalVolume)
+	assert.Greater(t, l.TotalVolume, volumeBefore)
 }
 
 func TestAddOrderPriceMismatch(t *testing.T) {
-	limitPrice := decimal.NewFromFloat(1.96)
+	limitPrice := testPrice(1.96)
 
 	l, _ := NewLimit(limitPrice)
 	o := createTestOrder(t)
@@ -100,7 +100,7 @@ func TestAddOrderPriceMismatch(t *testing.T) {
 }
 
 func TestRemoveOrder(t *testing.T) {
-	price := decimal.NewFromFloat(1.95)
+	price := testPrice(1.95)
 	l, _ := NewLimit(price)
 	o := createTestOrder(t)
 
@@ -109,8 +109,8 @@ func TestRemoveOrder(t *testing.T) {
 
 	removedOrder := l.RemoveOrder(e)
 	assert.Equal(t, removedOrder, o)
-	assert.True(t, l.TotalVolume.Equal(decimal.Zero))
-	assert.True(t, l.TotalVolume.LessThan(volumeBefore))
+	assert.Zero(t, l.TotalVolume)
+	assert.Less(t, l.TotalVolume, volumeBefore)
 }
 
 func TestNewOrderbook(t *testing.T) {
@@ -123,8 +123,9 @@ func TestOrderbookAddOrder(t *testing.T) {
 	ob := NewOrderbook()
 	o := createTestOrder(t)
 
-	p, err := ob.AddOrder(o)
+	trades, p, err := ob.AddOrder(o)
 
+	assert.Empty(t, trades)
 	assert.Nil(t, p)
 	assert.Nil(t, err)
 }
@@ -133,37 +134,394 @@ func TestOrderbookAddOrderDuplicate(t *testing.T) {
 	ob := NewOrderbook()
 	o1 := createTestOrder(t)
 
-	_, err := ob.AddOrder(o1)
+	_, _, err := ob.AddOrder(o1)
 	assert.Nil(t, err)
 
-	_, err = ob.AddOrder(o1)
+	_, _, err = ob.AddOrder(o1)
 	assert.Equal(t, err, ErrOrderExists)
 }
 
 func TestPlaceOrderWithoutLimit(t *testing.T) {
 	ob := NewOrderbook()
 	o := createTestOrder(t)
-	limits := make(map[string]*Limit)
 
-	e, err := ob.PlaceOrder(o, limits)
+	e, err := ob.PlaceOrder(o)
 	assert.Nil(t, err)
 	assert.NotNil(t, e)
+	assert.NotNil(t, ob.layLevels.get(o.Price))
+	assert.Equal(t, o.Price, ob.BestLay())
 }
 
 func TestPlaceOrderWithLimit(t *testing.T) {
 	ob := NewOrderbook()
 	o := createTestOrder(t)
-	limits := make(map[string]*Limit)
 
 	limit, err := NewLimit(o.Price)
 	assert.Nil(t, err)
 
-	strPrice := o.Price.String()
-	limits[strPrice] = limit
+	ob.layLevels.add(limit)
 
-	e, err := ob.PlaceOrder(o, limits)
+	e, err := ob.PlaceOrder(o)
 	assert.Nil(t, err)
 	assert.NotNil(t, e)
+	assert.Equal(t, 1, ob.layLevels.Len())
+	assert.Equal(t, 1, limit.Orders.Len())
+}
+
+func TestOrderbookFillOrderFull(t *testing.T) {
+	ob := NewOrderbook()
+	lay := newTestOrder(t, Lay, 2.0, 100.0)
+	_, _, err := ob.AddOrder(lay)
+	assert.Nil(t, err)
+
+	back := newTestOrder(t, Back, 2.0, 100.0)
+	_, p, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+	assert.Zero(t, back.Stake)
+	assert.Zero(t, lay.Stake)
+	assert.Empty(t, ob.orders)
+	assert.Zero(t, ob.layLevels.Len())
+	assert.Zero(t, ob.backLevels.Len())
+	assert.Zero(t, ob.BestLay())
+}
+
+func TestOrderbookFillOrderPartialRests(t *testing.T) {
+	ob := NewOrderbook()
+	lay := newTestOrder(t, Lay, 2.0, 40.0)
+	_, _, err := ob.AddOrder(lay)
+	assert.Nil(t, err)
+
+	back := newTestOrder(t, Back, 2.0, 100.0)
+	_, p, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Equal(t, p, back)
+	assert.Equal(t, testAmount(60.0), p.Stake)
+	assert.Zero(t, ob.layLevels.Len())
+	assert.Contains(t, ob.orders, back.Id)
+	assert.Equal(t, back.Price, ob.BestBack())
+	assert.Equal(t, p.Stake, ob.backLevels.get(back.Price).TotalVolume)
+}
+
+func TestOrderbookFillOrderPriceTimePriority(t *testing.T) {
+	ob := NewOrderbook()
+	first := newTestOrder(t, Back, 2.0, 30.0)
+	second := newTestOrder(t, Back, 2.0, 30.0)
+	better := newTestOrder(t, Back, 1.9, 30.0)
+	worse := newTestOrder(t, Back, 2.2, 30.0)
+	for _, o := range []*Order{first, second, better, worse} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, better.Price, ob.BestBack())
+
+	lay := newTestOrder(t, Lay, 2.0, 50.0)
+	_, p, err := ob.AddOrder(lay)
+
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+	assert.Zero(t, better.Stake)
+	assert.Equal(t, testAmount(10.0), first.Stake)
+	assert.Equal(t, testAmount(30.0), second.Stake)
+	assert.Equal(t, testAmount(30.0), worse.Stake)
+	assert.NotContains(t, ob.orders, better.Id)
+	assert.Equal(t, first.Price, ob.BestBack())
+	assert.Equal(t, testAmount(40.0), ob.backLevels.get(first.Price).TotalVolume)
+}
+
+func TestOrderbookTrades(t *testing.T) {
+	ob := NewOrderbook()
+	first := newTestOrder(t, Lay, 2.1, 30.0)
+	second := newTestOrder(t, Lay, 2.0, 30.0)
+	for _, o := range []*Order{first, second} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	back := newTestOrder(t, Back, 2.0, 50.0)
+	trades, p, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+	assertTrades(t, []Trade{
+		{Seq: 1, MakerId: first.Id, TakerId: back.Id, Side: Back, Price: first.Price, Stake: testAmount(30.0)},
+		{Seq: 2, MakerId: second.Id, TakerId: back.Id, Side: Back, Price: second.Price, Stake: testAmount(20.0)},
+	}, trades)
+
+	lay := newTestOrder(t, Lay, 2.0, 10.0)
+	_, _, err = ob.AddOrder(lay)
+	assert.Nil(t, err)
+	back = newTestOrder(t, Back, 1.5, 5.0)
+	trades, _, err = ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, uint64(3), trades[0].Seq)
+	assert.Equal(t, second.Id, trades[0].MakerId)
+	assert.Equal(t, second.Price, trades[0].Price)
+}
+
+func TestOrderbookNoCross(t *testing.T) {
+	ob := NewOrderbook()
+	lay := newTestOrder(t, Lay, 2.0, 100.0)
+	_, _, err := ob.AddOrder(lay)
+	assert.Nil(t, err)
+
+	back := newTestOrder(t, Back, 2.02, 100.0)
+	_, p, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+	assert.Equal(t, testAmount(100.0), back.Stake)
+	assert.Equal(t, lay.Price, ob.BestLay())
+	assert.Equal(t, back.Price, ob.BestBack())
+	assert.Len(t, ob.orders, 2)
+}
+
+func TestOrderbookCancelOrder(t *testing.T) {
+	ob := NewOrderbook()
+	best := newTestOrder(t, Back, 1.9, 10.0)
+	next := newTestOrder(t, Back, 2.0, 20.0)
+	for _, o := range []*Order{best, next} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	o, err := ob.CancelOrder(best.Id)
+
+	assert.Nil(t, err)
+	assert.Equal(t, best, o)
+	assert.NotContains(t, ob.orders, best.Id)
+	assert.Nil(t, ob.backLevels.get(best.Price))
+	assert.Equal(t, next.Price, ob.BestBack())
+
+	_, err = ob.CancelOrder(best.Id)
+	assert.Equal(t, ErrOrderNotFound, err)
+
+	_, err = ob.CancelOrder(next.Id)
+	assert.Nil(t, err)
+	assert.Zero(t, ob.backLevels.Len())
+	assert.Zero(t, ob.BestBack())
+}
+
+func TestOrderbookCancelOrderKeepsLevel(t *testing.T) {
+	ob := NewOrderbook()
+	first := newTestOrder(t, Lay, 2.0, 10.0)
+	second := newTestOrder(t, Lay, 2.0, 20.0)
+	for _, o := range []*Order{first, second} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	_, err := ob.CancelOrder(first.Id)
+
+	assert.Nil(t, err)
+	limit := ob.layLevels.get(second.Price)
+	assert.Equal(t, second.Stake, limit.TotalVolume)
+	assert.Equal(t, 1, limit.Orders.Len())
+	assert.Equal(t, second.Price, ob.BestLay())
+}
+
+func TestOrderbookReduceOrder(t *testing.T) {
+	ob := NewOrderbook()
+	first := newTestOrder(t, Lay, 2.0, 10.0)
+	second := newTestOrder(t, Lay, 2.0, 20.0)
+	for _, o := range []*Order{first, second} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	o, err := ob.ReduceOrder(first.Id, testAmount(4.0))
+
+	assert.Nil(t, err)
+	assert.Equal(t, testAmount(6.0), o.Stake)
+	limit := ob.layLevels.get(first.Price)
+	assert.Equal(t, testAmount(26.0), limit.TotalVolume)
+	assert.Equal(t, first, limit.Orders.Front().Value.(*Order))
+
+	_, err = ob.ReduceOrder(first.Id, 0)
+	assert.Equal(t, ErrInvalidStake, err)
+	_, err = ob.ReduceOrder("unknown", testAmount(1.0))
+	assert.Equal(t, ErrOrderNotFound, err)
+
+	_, err = ob.ReduceOrder(first.Id, testAmount(6.0))
+	assert.Nil(t, err)
+	assert.NotContains(t, ob.orders, first.Id)
+	assert.Equal(t, second.Stake, limit.TotalVolume)
+}
+
+func TestOrderbookAmendOrderStake(t *testing.T) {
+	ob := NewOrderbook()
+	first := newTestOrder(t, Back, 3.0, 10.0)
+	second := newTestOrder(t, Back, 3.0, 10.0)
+	for _, o := range []*Order{first, second} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+	limit := ob.backLevels.get(first.Price)
+
+	// reducing stake keeps time priority
+	trades, o, err := ob.AmendOrder(first.Id, first.Price, testAmount(4))
+
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Equal(t, first, o)
+	assert.Equal(t, testAmount(4), first.Stake)
+	assert.Equal(t, testAmount(14), limit.TotalVolume)
+	assert.Equal(t, []*Order{first, second}, ob.Orders(Back))
+
+	// increasing stake moves order to the back of the level
+	_, _, err = ob.AmendOrder(first.Id, first.Price, testAmount(15))
+
+	assert.Nil(t, err)
+	assert.Equal(t, Resting, first.Status)
+	assert.Equal(t, testAmount(25), limit.TotalVolume)
+	assert.Equal(t, []*Order{second, first}, ob.Orders(Back))
+
+	_, _, err = ob.AmendOrder(second.Id, second.Price, second.Stake)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Order{second, first}, ob.Orders(Back))
+}
+
+func TestOrderbookAmendOrderPrice(t *testing.T) {
+	ob := NewOrderbook()
+	lay := newTestOrder(t, Lay, 2.0, 10.0)
+	first := newTestOrder(t, Back, 3.0, 10.0)
+	second := newTestOrder(t, Back, 3.0, 10.0)
+	for _, o := range []*Order{lay, first, second} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	// new price loses priority even when moved back
+	_, _, err := ob.AmendOrder(first.Id, testPrice(3.5), first.Stake)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Stake, ob.backLevels.get(testPrice(3.5)).TotalVolume)
+	_, _, err = ob.AmendOrder(first.Id, testPrice(3.0), first.Stake)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Order{second, first}, ob.Orders(Back))
+	assert.Nil(t, ob.backLevels.get(testPrice(3.5)))
+
+	// crossing price is matched at once
+	trades, o, err := ob.AmendOrder(second.Id, testPrice(2.0), testAmount(6))
+
+	assert.Nil(t, err)
+	assert.Equal(t, second, o)
+	assertTrades(t, []Trade{{Seq: 1, MakerId: lay.Id, TakerId: second.Id, Side: Back, Price: testPrice(2.0), Stake: testAmount(6)}}, trades)
+	assert.Equal(t, Filled, second.Status)
+	assert.Equal(t, testAmount(4), lay.Stake)
+	assert.Equal(t, []*Order{first}, ob.Orders(Back))
+}
+
+func TestOrderbookAmendOrderInvalid(t *testing.T) {
+	ob := NewOrderbook()
+	o := newTestOrder(t, Back, 3.0, 10.0)
+	_, _, err := ob.AddOrder(o)
+	assert.Nil(t, err)
+
+	_, _, err = ob.AmendOrder("unknown", o.Price, o.Stake)
+	assert.Equal(t, ErrOrderNotFound, err)
+	_, _, err = ob.AmendOrder(o.Id, o.Price, 0)
+	assert.Equal(t, ErrInvalidStake, err)
+	_, _, err = ob.AmendOrder(o.Id, testPrice(3.01), o.Stake)
+	assert.Equal(t, ErrPriceNotOnLadder, err)
+	assert.Equal(t, []*Order{o}, ob.Orders(Back))
+}
+
+func TestTimeInForceString(t *testing.T) {
+	assert.Equal(t, "GTC", GoodTilCancel.String())
+	assert.Equal(t, "IOC", ImmediateOrCancel.String())
+	assert.Equal(t, "FOK", FillOrKill.String())
+}
+
+func TestOrderbookOrderStatus(t *testing.T) {
+	ob := NewOrderbook()
+	maker := newTestOrder(t, Lay, 2.0, 10.0)
+	assert.Equal(t, Pending, maker.Status)
+
+	_, _, err := ob.AddOrder(maker)
+	assert.Nil(t, err)
+	assert.Equal(t, Resting, maker.Status)
+
+	taker := newTestOrder(t, Back, 2.0, 15.0)
+	_, _, err = ob.AddOrder(taker)
+	assert.Nil(t, err)
+	assert.Equal(t, Filled, maker.Status)
+	assert.Equal(t, Resting, taker.Status)
+
+	_, _, err = ob.AddOrder(newTestOrder(t, Lay, 2.0, 5.0))
+	assert.Nil(t, err)
+	assert.Equal(t, Filled, taker.Status)
+
+	o := newTestOrder(t, Back, 3.0, 5.0)
+	_, _, err = ob.AddOrder(o)
+	assert.Nil(t, err)
+	_, err = ob.CancelOrder(o.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, Cancelled, o.Status)
+}
+
+func TestOrderbookImmediateOrCancel(t *testing.T) {
+	ob := NewOrderbook()
+	_, _, err := ob.AddOrder(newTestOrder(t, Lay, 2.0, 10.0))
+	assert.Nil(t, err)
+
+	o := newTestOrder(t, Back, 2.0, 15.0)
+	o.TimeInForce = ImmediateOrCancel
+	trades, p, err := ob.AddOrder(o)
+
+	assert.Nil(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, o, p)
+	assert.Equal(t, Cancelled, o.Status)
+	assert.Equal(t, testAmount(5), o.Stake)
+	assert.NotContains(t, ob.orders, o.Id)
+	assert.Zero(t, ob.backLevels.Len())
+
+	o = newTestOrder(t, Back, 2.0, 15.0)
+	o.TimeInForce = ImmediateOrCancel
+	trades, p, err = ob.AddOrder(o)
+
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Nil(t, p)
+	assert.Equal(t, Cancelled, o.Status)
+	assert.Empty(t, ob.orders)
+}
+
+func TestOrderbookFillOrKill(t *testing.T) {
+	ob := NewOrderbook()
+	for _, o := range []*Order{
+		newTestOrder(t, Lay, 2.0, 10.0),
+		newTestOrder(t, Lay, 2.2, 10.0),
+		newTestOrder(t, Lay, 1.9, 10.0),
+	} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	o := newTestOrder(t, Back, 2.0, 25.0)
+	o.TimeInForce = FillOrKill
+	trades, p, err := ob.AddOrder(o)
+
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Nil(t, p)
+	assert.Equal(t, Killed, o.Status)
+	assert.Equal(t, testAmount(25), o.Stake)
+	assert.Len(t, ob.orders, 3)
+
+	o = newTestOrder(t, Back, 2.0, 20.0)
+	o.TimeInForce = FillOrKill
+	trades, p, err = ob.AddOrder(o)
+
+	assert.Nil(t, err)
+	assert.Len(t, trades, 2)
+	assert.Nil(t, p)
+	assert.Equal(t, Filled, o.Status)
+	assert.Len(t, ob.orders, 1)
 }
 
 // createTestOrder - helper to create proper test order
@@ -179,3 +537,40 @@ func createTestOrder(t *testing.T) (o *Order) {
 	}
 	return
 }
+
+// newTestOrder - helper to create order with given side, price and stake
+func newTestOrder(t *testing.T, side Side, price, stake float64) *Order {
+	t.Helper()
+
+	o, err := NewOrder(side, decimal.NewFromFloat(price), decimal.NewFromFloat(stake))
+	if err != nil {
+		t.Fatalf("Error creating test order: %s", err)
+	}
+	return o
+}
+
+func TestOrderbookOrders(t *testing.T) {
+	ob := NewOrderbook()
+	first := newTestOrder(t, Back, 2.2, 10.0)
+	second := newTestOrder(t, Back, 2.1, 10.0)
+	third := newTestOrder(t, Back, 2.2, 10.0)
+	for _, o := range []*Order{first, second, third} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, []*Order{second, first, third}, ob.Orders(Back))
+	assert.Empty(t, ob.Orders(Lay))
+}
+
+// assertTrades - compares trades one by one
+func assertTrades(t *testing.T, expected, actual []Trade) {
+	t.Helper()
+
+	if !assert.Len(t, actual, len(expected)) {
+		return
+	}
+	for i := range expected {
+		assert.Equal(t, expected[i], actual[i], "trade %d", i)
+	}
+}
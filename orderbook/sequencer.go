@@ -0,0 +1,287 @@
+package orderbook
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CommandType - kind of request processed by Sequencer
+type CommandType int
+
+const (
+	PlaceCommand CommandType = iota
+	CancelCommand
+	ReduceCommand
+	AmendCommand
+	SuspendCommand
+	ReopenCommand
+	TurnInPlayCommand
+	CloseCommand
+	SettleCommand
+	SPBetCommand
+	ReleaseCommand // release of held orders, recorded in journal only
+)
+
+func (c CommandType) String() string {
+	return [...]string{"Place", "Cancel", "Reduce", "Amend", "Suspend", "Reopen", "TurnInPlay", "Close", "Settle", "SPBet", "Release"}[c]
+}
+
+// Command - request to change the market
+type Command struct {
+	Type      CommandType     `json:"type"`
+	Selection string          `json:"selection,omitempty"` // place, sp bet
+	Order     *Order          `json:"order,omitempty"`     // place
+	Bet       *SPBet          `json:"bet,omitempty"`       // sp bet
+	OrderId   string          `json:"order_id,omitempty"`  // cancel, reduce, amend
+	Price     decimal.Decimal `json:"price"`               // amend
+	Amount    decimal.Decimal `json:"amount"`              // reduce, new stake for amend
+}
+
+// copy - command with its own copy of the order and the bet, so that the
+// command can be journaled as it was submitted
+func (c Command) copy() Command {
+	if c.Order != nil {
+		o := *c.Order
+		c.Order = &o
+	}
+	if c.Bet != nil {
+		b := *c.Bet
+		c.Bet = &b
+	}
+	return c
+}
+
+// Result - outcome of processed command. Order is a copy of the placed,
+// reduced, amended or cancelled order, as the market keeps changing resting
+// orders once the result is returned
+type Result struct {
+	Command  Command
+	Trades   []Trade
+	Order    *Order
+	Err      error
+	Released bool   // order was held by bet delay, Order is its copy as released
+	Seq      uint64 // position among results of the sequencer from 1, zero when not processed
+}
+
+// detach - copy of the order outside of the book
+func (o *Order) detach() *Order {
+	c := *o
+	c.limit = nil
+	return &c
+}
+
+// execute - applies command to the market, result holds the command as it
+// was submitted
+func (m *Market) execute(cmd Command) (res Result) {
+	res.Command = cmd.copy()
+	defer func() {
+		if res.Order != nil {
+			res.Order = res.Order.detach()
+		}
+	}()
+
+	switch cmd.Type {
+	case PlaceCommand:
+		res.Order = cmd.Order
+		res.Trades, _, res.Err = m.AddOrder(cmd.Selection, cmd.Order)
+	case CancelCommand:
+		res.Order, res.Err = m.CancelOrder(cmd.OrderId)
+	case ReduceCommand:
+		res.Order, res.Err = m.ReduceOrder(cmd.OrderId, cmd.Amount)
+	case AmendCommand:
+		res.Trades, res.Order, res.Err = m.AmendOrder(cmd.OrderId, cmd.Price, cmd.Amount)
+	case SuspendCommand:
+		res.Err = m.Suspend()
+	case ReopenCommand:
+		res.Err = m.Reopen()
+	case TurnInPlayCommand:
+		_, _, res.Err = m.TurnInPlay()
+	case CloseCommand:
+		m.Close()
+	case SettleCommand:
+		res.Err = m.Settle()
+	case SPBetCommand:
+		res.Err = m.PlaceSPBet(cmd.Selection, cmd.Bet)
+	default:
+		res.Err = ErrUnknownCommand
+	}
+	return
+}
+
+type request struct {
+	cmd   Command
+	reply chan Result
+}
+
+// Sequencer - single writer event loop of the market. Market is not safe for
+// concurrent use, so every access goes through the loop goroutine which
+// processes commands one by one and publishes their results in order. Orders
+// held by bet delay are released by the loop when they are due, their
+// results are published only to results channel.
+//
+// With Journal set every accepted command is appended to it before its result
+// is published. The loop stops when the journal fails, as the market would
+// not be recoverable any more
+type Sequencer struct {
+	Journal       Journal // optional log of accepted commands, set before Start
+	SnapshotEvery uint64  // journal entries between snapshots, zero disables them
+
+	market   *Market
+	seq      uint64 // last result
+	requests chan request
+	views    chan func(*Market)
+	results  chan<- Result
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// NewSequencer - creates sequencer of the market, every result is also sent
+// to results channel unless it is nil
+func NewSequencer(m *Market, results chan<- Result) *Sequencer {
+	return &Sequencer{
+		market:   m,
+		requests: make(chan request),
+		views:    make(chan func(*Market)),
+		results:  results,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start - runs the loop in a new goroutine. With Journal set snapshot of the
+// market is written first, so that journal can always be replayed
+func (s *Sequencer) Start() error {
+	if s.Journal != nil {
+		if err := s.Journal.Snapshot(s.market.Snapshot()); err != nil {
+			return err
+		}
+	}
+	go s.run()
+	return nil
+}
+
+// Stop - stops the loop after the command being processed
+func (s *Sequencer) Stop() {
+	close(s.quit)
+	<-s.done
+}
+
+func (s *Sequencer) run() {
+	defer close(s.done)
+
+	for {
+		var timer *time.Timer
+		var release <-chan time.Time
+		if due, ok := s.market.nextRelease(); ok {
+			timer = time.NewTimer(time.Until(due))
+			release = timer.C
+		}
+
+		var err error
+		select {
+		case r := <-s.requests:
+			var res Result
+			res, err = s.process(r.cmd)
+			res = s.publish(res)
+			r.reply <- res
+		case fn := <-s.views:
+			fn(s.market)
+		case now := <-release:
+			now = now.Round(0)
+			results := s.market.Release(now)
+			if len(results) > 0 {
+				err = s.record(Command{Type: ReleaseCommand}, now)
+			}
+			for _, res := range results {
+				s.publish(res)
+			}
+		case <-s.quit:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil || s.stopping() {
+			return
+		}
+	}
+}
+
+func (s *Sequencer) stopping() bool {
+	select {
+	case <-s.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// process - executes command at the current time of the market clock and
+// journals it once accepted
+func (s *Sequencer) process(cmd Command) (res Result, err error) {
+	now := s.market.clock().Round(0)
+	s.market.at(now, func() { res = s.market.execute(cmd) })
+	if res.Err != nil {
+		return res, nil
+	}
+
+	if err = s.record(res.Command, now); err != nil {
+		res.Err = err
+	}
+	return
+}
+
+// record - appends accepted command to the journal and takes snapshot when
+// it is due
+func (s *Sequencer) record(cmd Command, now time.Time) error {
+	if s.Journal == nil {
+		return nil
+	}
+
+	s.market.entry++
+	if err := s.Journal.Append(Entry{Seq: s.market.entry, Time: now, Command: cmd}); err != nil {
+		return err
+	}
+	if s.SnapshotEvery > 0 && s.market.entry%s.SnapshotEvery == 0 {
+		return s.Journal.Snapshot(s.market.Snapshot())
+	}
+	return nil
+}
+
+// publish - numbers the result and sends it to results channel
+func (s *Sequencer) publish(res Result) Result {
+	s.seq++
+	res.Seq = s.seq
+	if s.results != nil {
+		s.results <- res
+	}
+	return res
+}
+
+// Submit - sends command to the loop and waits for its result
+func (s *Sequencer) Submit(cmd Command) Result {
+	reply := make(chan Result, 1)
+	select {
+	case s.requests <- request{cmd: cmd, reply: reply}:
+		return <-reply
+	case <-s.done:
+		return Result{Command: cmd, Err: ErrSequencerStopped}
+	case <-s.quit:
+		return Result{Command: cmd, Err: ErrSequencerStopped}
+	}
+}
+
+// View - runs read-only fn inside the loop, e.g. to take market depth
+func (s *Sequencer) View(fn func(m *Market)) error {
+	done := make(chan struct{})
+	select {
+	case s.views <- func(m *Market) { fn(m); close(done) }:
+		<-done
+		return nil
+	case <-s.done:
+		return ErrSequencerStopped
+	case <-s.quit:
+		return ErrSequencerStopped
+	}
+}
@@ -0,0 +1,192 @@
+package orderbook
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequencerCommands(t *testing.T) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	s := NewSequencer(m, nil)
+	s.Start()
+	defer s.Stop()
+
+	o := newTestOrder(t, Back, 3.0, 10.0)
+	res := s.Submit(Command{Type: PlaceCommand, Selection: "home", Order: o})
+	assert.Nil(t, res.Err)
+	assert.Equal(t, o.Id, res.Order.Id)
+	assert.Equal(t, Resting, res.Order.Status)
+
+	res = s.Submit(Command{Type: ReduceCommand, OrderId: o.Id, Amount: decimal.NewFromInt(4)})
+	assert.Nil(t, res.Err)
+	assert.Equal(t, testAmount(6), res.Order.Stake)
+
+	res = s.Submit(Command{Type: AmendCommand, OrderId: o.Id, Price: decimal.NewFromFloat(3.5), Amount: decimal.NewFromInt(8)})
+	assert.Nil(t, res.Err)
+	assert.Equal(t, testPrice(3.5), res.Order.Price)
+	assert.Equal(t, testAmount(8), res.Order.Stake)
+
+	res = s.Submit(Command{Type: CancelCommand, OrderId: o.Id})
+	assert.Nil(t, res.Err)
+	res = s.Submit(Command{Type: CancelCommand, OrderId: o.Id})
+	assert.Equal(t, ErrOrderNotFound, res.Err)
+
+	res = s.Submit(Command{Type: CommandType(42)})
+	assert.Equal(t, ErrUnknownCommand, res.Err)
+
+	var depth Depth
+	err := s.View(func(m *Market) { depth, _ = m.Depth("home", 1) })
+	assert.Nil(t, err)
+	assert.Empty(t, depth.Back)
+}
+
+func TestSequencerStopped(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	s := NewSequencer(m, nil)
+	s.Start()
+	s.Stop()
+
+	res := s.Submit(Command{Type: CancelCommand, OrderId: "1"})
+	assert.Equal(t, ErrSequencerStopped, res.Err)
+	assert.Equal(t, ErrSequencerStopped, s.View(func(m *Market) {}))
+}
+
+func TestSequencerConcurrentSubmitters(t *testing.T) {
+	const (
+		submitters = 16
+		orders     = 200
+	)
+
+	m, _ := NewMarket("1", "home", "draw", "away")
+	results := make(chan Result, 64)
+	s := NewSequencer(m, results)
+	s.Start()
+
+	var published []Result
+	collected := make(chan struct{})
+	go func() {
+		for r := range results {
+			published = append(published, r)
+		}
+		close(collected)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < submitters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < orders; j++ {
+				side := Side((i + j) % 2)
+				o, _ := NewOrder(side, decimal.NewFromInt(2), decimal.NewFromInt(1))
+				res := s.Submit(Command{Type: PlaceCommand, Selection: "home", Order: o})
+				assert.Nil(t, res.Err)
+				if j%10 == 0 {
+					s.Submit(Command{Type: CancelCommand, OrderId: o.Id})
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var resting Amount
+	var count int
+	err := s.View(func(m *Market) {
+		home, _ := m.Book("home")
+		count = len(home.orders)
+		for _, side := range []Side{Back, Lay} {
+			for _, o := range home.Orders(side) {
+				resting += o.Stake
+			}
+		}
+	})
+	assert.Nil(t, err)
+	s.Stop()
+	close(results)
+	<-collected
+
+	var seq uint64
+	var matched, cancelled Amount
+	for _, r := range published {
+		for _, tr := range r.Trades {
+			assert.Equal(t, seq+1, tr.Seq)
+			seq = tr.Seq
+			matched += tr.Stake
+		}
+		if r.Command.Type == CancelCommand && r.Err == nil {
+			cancelled += r.Order.Stake
+		}
+	}
+
+	total := testAmount(submitters * orders)
+	assert.Equal(t, total, 2*matched+cancelled+resting,
+		"matched %s, cancelled %s, resting %s", matched, cancelled, resting)
+	assert.Equal(t, testAmount(float64(count)), resting)
+}
+
+func TestSequencerBetDelay(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	m.BetDelay = 20 * time.Millisecond
+	_, _, err := m.TurnInPlay()
+	assert.Nil(t, err)
+
+	results := make(chan Result, 8)
+	s := NewSequencer(m, results)
+	s.Start()
+	defer s.Stop()
+
+	o := newTestOrder(t, Back, 3.0, 10.0)
+	res := s.Submit(Command{Type: PlaceCommand, Selection: "home", Order: o})
+	assert.Nil(t, res.Err)
+	assert.Empty(t, res.Trades)
+	assert.Equal(t, uint64(1), res.Seq)
+	assert.Equal(t, res, <-results)
+
+	select {
+	case res = <-results:
+		assert.Nil(t, res.Err)
+		assert.True(t, res.Released)
+		assert.Equal(t, uint64(2), res.Seq)
+		assert.Equal(t, o.Id, res.Order.Id)
+	case <-time.After(time.Second):
+		t.Fatal("held order was not released")
+	}
+	var status OrderStatus
+	assert.Nil(t, s.View(func(m *Market) { status = o.Status }))
+	assert.Equal(t, Resting, status)
+
+	res = s.Submit(Command{Type: SuspendCommand})
+	assert.Nil(t, res.Err)
+	res = s.Submit(Command{Type: PlaceCommand, Selection: "home", Order: newTestOrder(t, Back, 3.0, 10.0)})
+	assert.Equal(t, ErrMarketSuspended, res.Err)
+	res = s.Submit(Command{Type: ReopenCommand})
+	assert.Nil(t, res.Err)
+}
+
+func TestSequencerResultOrderDetached(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	s := NewSequencer(m, nil)
+	s.Start()
+	defer s.Stop()
+
+	lay := newTestOrder(t, Lay, 3.0, 10.0)
+	placed := s.Submit(Command{Type: PlaceCommand, Selection: "home", Order: lay})
+	assert.Nil(t, placed.Err)
+	amended := s.Submit(Command{Type: AmendCommand, OrderId: lay.Id, Price: decimal.NewFromInt(3), Amount: decimal.NewFromInt(9)})
+	assert.Nil(t, amended.Err)
+
+	res := s.Submit(Command{Type: PlaceCommand, Selection: "home", Order: newTestOrder(t, Back, 3.0, 4.0)})
+	assert.Len(t, res.Trades, 1)
+
+	// results keep the order as it was when they were returned
+	assert.Equal(t, testAmount(10), placed.Order.Stake)
+	assert.Equal(t, testAmount(10), placed.Command.Order.Stake)
+	assert.Equal(t, testAmount(9), amended.Order.Stake)
+	var stake Amount
+	assert.Nil(t, s.View(func(*Market) { stake = lay.Stake }))
+	assert.Equal(t, testAmount(5), stake)
+}
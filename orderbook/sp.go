@@ -0,0 +1,369 @@
+package orderbook
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// SPBet - starting price bet placed before the off. Market on close bet has
+// zero Limit and takes any SP, limit on close bet is matched only when SP is
+// at or above Limit for backs and at or below Limit for lays. Amount is the
+// stake for backs and the liability for lays
+type SPBet struct {
+	Id     string          `json:"id"`
+	Owner  string          `json:"owner"`
+	Side   Side            `json:"side"`
+	Limit  decimal.Decimal `json:"limit"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+func NewSPBet(side Side, limit, amount decimal.Decimal) (*SPBet, error) {
+	if amount.Sign() <= 0 {
+		return nil, ErrInvalidStake
+	}
+
+	if !limit.IsZero() {
+		if err := DefaultLadder.Validate(limit); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SPBet{
+		Id:     nextId(),
+		Side:   side,
+		Limit:  limit,
+		Amount: amount,
+	}, nil
+}
+
+// SPMatch - part of SP bet or unmatched exchange order matched at SP
+type SPMatch struct {
+	Id       string
+	Owner    string
+	Side     Side
+	Stake    decimal.Decimal // matched backer's stake
+	Exchange bool            // exchange order rather than SP bet
+}
+
+// Reconciliation - starting price of the selection and bets matched at it.
+// Price is zero when there was nothing to match
+type Reconciliation struct {
+	Selection string
+	Price     decimal.Decimal
+	Matched   decimal.Decimal
+	Matches   []SPMatch
+}
+
+// participant - SP bet or exchange order taking part in reconciliation
+type participant struct {
+	id       string
+	owner    string
+	side     Side
+	limit    decimal.Decimal // zero for any price
+	amount   decimal.Decimal // stake, liability for SP lays
+	exchange bool
+}
+
+func (p *participant) accepts(price decimal.Decimal) bool {
+	if p.limit.IsZero() {
+		return true
+	}
+	if p.side == Back {
+		return price.GreaterThanOrEqual(p.limit)
+	}
+	return price.LessThanOrEqual(p.limit)
+}
+
+// stake - backer's stake participant can be matched for at price
+func (p *participant) stake(price decimal.Decimal) decimal.Decimal {
+	if p.side == Lay && !p.exchange {
+		return p.amount.Div(price.Sub(minPrice))
+	}
+	return p.amount
+}
+
+// volumes - backers' stake demanded and layers' stake supplied at price
+func volumes(participants []*participant, price decimal.Decimal) (demand, supply decimal.Decimal) {
+	for _, p := range participants {
+		if !p.accepts(price) {
+			continue
+		}
+		if p.side == Back {
+			demand = demand.Add(p.stake(price))
+		} else {
+			supply = supply.Add(p.stake(price))
+		}
+	}
+	return
+}
+
+// between - backers' stake, exchange layers' stake and SP layers' liability
+// of participants accepting every price strictly between a and b
+func between(participants []*participant, a, b decimal.Decimal) (demand, fixed, liability decimal.Decimal) {
+	for _, p := range participants {
+		if !p.accepts(a) || !p.accepts(b) {
+			continue
+		}
+		switch {
+		case p.side == Back:
+			demand = demand.Add(p.amount)
+		case p.exchange:
+			fixed = fixed.Add(p.amount)
+		default:
+			liability = liability.Add(p.amount)
+		}
+	}
+	return
+}
+
+// breakpoints - sorted distinct prices where set of participants changes
+func breakpoints(participants []*participant) []decimal.Decimal {
+	points := []decimal.Decimal{DefaultLadder.Min(), DefaultLadder.Max()}
+	for _, p := range participants {
+		if !p.limit.IsZero() {
+			points = append(points, p.limit)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].LessThan(points[j]) })
+
+	res := points[:1]
+	for _, p := range points[1:] {
+		if !p.Equal(res[len(res)-1]) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// crossing - finds price where supply of layers meets demand of backers.
+// Demand grows and supply falls with the price, so the lowest breakpoint with
+// demand covering supply bounds the interval where they cross. Inside the
+// interval the set of participants is fixed and supply is C + L / (p - 1),
+// which gives the balanced price p = 1 + L / (D - C)
+func crossing(participants []*participant, points []decimal.Decimal) decimal.Decimal {
+	prev := decimal.Zero
+	for _, point := range points {
+		demand, supply := volumes(participants, point)
+		if supply.GreaterThan(demand) {
+			prev = point
+			continue
+		}
+		if prev.IsZero() {
+			return point
+		}
+
+		demand, fixed, liability := between(participants, prev, point)
+		if demand.LessThanOrEqual(fixed) {
+			return point
+		}
+
+		price := minPrice.Add(liability.Div(demand.Sub(fixed))).Round(stakePlaces)
+		if price.LessThan(prev) {
+			return prev
+		}
+		if price.GreaterThan(point) {
+			return point
+		}
+		return price
+	}
+	return prev
+}
+
+// highest - moves price up as long as supply still covers matched stake, so
+// among prices matching the same volume the best one for backers is taken
+func highest(participants []*participant, points []decimal.Decimal, price, matched decimal.Decimal) decimal.Decimal {
+	for _, point := range points {
+		if point.LessThanOrEqual(price) {
+			continue
+		}
+
+		_, supply := volumes(participants, point)
+		if supply.GreaterThanOrEqual(matched) {
+			price = point
+			continue
+		}
+
+		_, fixed, liability := between(participants, price, point)
+		if matched.LessThanOrEqual(fixed) || liability.IsZero() {
+			return price
+		}
+		p := minPrice.Add(liability.Div(matched.Sub(fixed))).RoundFloor(stakePlaces)
+		if p.GreaterThan(price) {
+			return p
+		}
+		return price
+	}
+	return price
+}
+
+// startingPrice - price matching the most stake, the highest one on ties
+func startingPrice(participants []*participant) decimal.Decimal {
+	points := breakpoints(participants)
+	price := crossing(participants, points)
+
+	demand, supply := volumes(participants, price)
+	return highest(participants, points, price, decimal.Min(demand, supply))
+}
+
+// allocate - splits matched stake between participants of one side, SP bets
+// are matched pro rata first and exchange orders take the rest in priority
+func allocate(participants []*participant, price, matched decimal.Decimal) (res []SPMatch) {
+	var bets, orders []*participant
+	total := decimal.Zero
+	for _, p := range participants {
+		if p.exchange {
+			orders = append(orders, p)
+		} else {
+			bets = append(bets, p)
+			total = total.Add(p.stake(price))
+		}
+	}
+
+	ratio := decimal.NewFromInt(1)
+	if total.GreaterThan(matched) {
+		ratio = matched.Div(total)
+	}
+
+	left := matched
+	for _, p := range bets {
+		stake := p.stake(price).Mul(ratio).RoundFloor(stakePlaces)
+		if stake.Sign() <= 0 {
+			continue
+		}
+		left = left.Sub(stake)
+		res = append(res, SPMatch{Id: p.id, Owner: p.owner, Side: p.side, Stake: stake})
+	}
+
+	for _, p := range orders {
+		if left.Sign() <= 0 {
+			break
+		}
+		stake := decimal.Min(left, p.amount)
+		left = left.Sub(stake)
+		res = append(res, SPMatch{Id: p.id, Owner: p.owner, Side: p.side, Stake: stake, Exchange: true})
+	}
+	return
+}
+
+// Reconcile - computes starting price of the selection from SP bets and
+// unmatched orders of the book, which act as limit on close bets at their
+// own price. Neither the book nor the bets are modified
+func Reconcile(selection string, ob *Orderbook, bets []*SPBet) Reconciliation {
+	res := Reconciliation{Selection: selection}
+
+	var participants []*participant
+	for _, b := range bets {
+		participants = append(participants, &participant{
+			id:     b.Id,
+			owner:  b.Owner,
+			side:   b.Side,
+			limit:  b.Limit,
+			amount: b.Amount,
+		})
+	}
+	for _, side := range []Side{Back, Lay} {
+		for _, o := range ob.Orders(side) {
+			participants = append(participants, &participant{
+				id:       o.Id,
+				owner:    o.Owner,
+				side:     o.Side,
+				limit:    o.Price.Decimal(),
+				amount:   o.Stake.Decimal(),
+				exchange: true,
+			})
+		}
+	}
+
+	price := startingPrice(participants)
+	demand, supply := volumes(participants, price)
+	matched := decimal.Min(demand, supply).RoundFloor(stakePlaces)
+	if matched.Sign() <= 0 {
+		return res
+	}
+
+	var backs, lays []*participant
+	for _, p := range participants {
+		if !p.accepts(price) {
+			continue
+		}
+		if p.side == Back {
+			backs = append(backs, p)
+		} else {
+			lays = append(lays, p)
+		}
+	}
+
+	res.Price = price
+	res.Matched = matched
+	res.Matches = append(allocate(backs, price, matched), allocate(lays, price, matched)...)
+	return res
+}
+
+// PlaceSPBet - accepts starting price bet on the selection before the off
+func (m *Market) PlaceSPBet(selection string, bet *SPBet) error {
+	if m.status != Open {
+		return ErrMarketStatus
+	}
+	if _, err := m.Book(selection); err != nil {
+		return err
+	}
+	if err := m.checkFunds(bet.Owner, &risk{selection: selection, side: bet.Side, loss: bet.Amount}); err != nil {
+		return err
+	}
+
+	m.spBets[selection] = append(m.spBets[selection], bet)
+	return nil
+}
+
+// reconcile - matches SP bets and orders converted at the off on the
+// selection at its starting price. Unmatched parts of converted orders lapse
+// and matched exchange orders are reduced in the book
+func (m *Market) reconcile(selection string, converted []*Order) Reconciliation {
+	ob := m.books[selection]
+
+	bets := m.spBets[selection]
+	orders := make(map[string]*Order)
+	for _, o := range converted {
+		amount := o.Stake.Decimal()
+		if o.Side == Lay {
+			amount = o.Liability()
+		}
+		bets = append(bets, &SPBet{Id: o.Id, Owner: o.Owner, Side: o.Side, Limit: o.Price.Decimal(), Amount: amount})
+		orders[o.Id] = o
+	}
+
+	rec := Reconcile(selection, ob, bets)
+	// starting price is rounded to a hundredth and matched stakes to a minor
+	// unit, so both convert exactly
+	price, _ := NewPrice(rec.Price)
+	for _, match := range rec.Matches {
+		stake := amountFloor(match.Stake)
+		m.record(selection, match.Owner, match.Side, price, stake)
+		if match.Exchange {
+			if o := ob.take(match.Id, stake); o != nil && o.Status == Filled {
+				m.account(o.Owner).remove(selection, o)
+			}
+		} else if o, ok := orders[match.Id]; ok {
+			// lays were converted by liability, used part of it is turned
+			// back into stake at the order price
+			used := match.Stake
+			if o.Side == Lay {
+				used = used.Mul(rec.Price.Sub(minPrice)).Div(o.Price.Decimal().Sub(minPrice))
+			}
+			o.Stake = amountFloor(o.Stake.Decimal().Sub(used))
+		}
+	}
+
+	for _, o := range converted {
+		m.account(o.Owner).remove(selection, o)
+		if o.Stake <= 0 {
+			o.Stake = 0
+			o.Status = Filled
+		} else {
+			o.Status = Cancelled
+		}
+	}
+	delete(m.spBets, selection)
+	return rec
+}
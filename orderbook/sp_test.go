@@ -0,0 +1,155 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSPBet - helper to create SP bet of the owner, zero limit is market on close
+func newTestSPBet(t *testing.T, owner string, side Side, limit, amount float64) *SPBet {
+	t.Helper()
+
+	b, err := NewSPBet(side, decimal.NewFromFloat(limit), decimal.NewFromFloat(amount))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Owner = owner
+	return b
+}
+
+// assertMatches - compares matched stakes by participant id
+func assertMatches(t *testing.T, expected map[string]float64, actual []SPMatch) {
+	t.Helper()
+
+	if !assert.Len(t, actual, len(expected)) {
+		return
+	}
+	for _, m := range actual {
+		stake := decimal.NewFromFloat(expected[m.Id])
+		assert.True(t, stake.Equal(m.Stake), "match %s stake: %s != %s", m.Id, stake, m.Stake)
+	}
+}
+
+func TestNewSPBet(t *testing.T) {
+	_, err := NewSPBet(Back, decimal.Zero, decimal.Zero)
+	assert.Equal(t, ErrInvalidStake, err)
+
+	_, err = NewSPBet(Back, decimal.NewFromFloat(2.01), decimal.NewFromInt(10))
+	assert.Equal(t, ErrPriceNotOnLadder, err)
+
+	b, err := NewSPBet(Lay, decimal.Zero, decimal.NewFromInt(10))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, b.Id)
+	assert.True(t, b.Limit.IsZero())
+}
+
+func TestReconcileMarketOnClose(t *testing.T) {
+	back := newTestSPBet(t, "alice", Back, 0, 100)
+	lay := newTestSPBet(t, "bob", Lay, 0, 150)
+
+	rec := Reconcile("home", NewOrderbook(), []*SPBet{back, lay})
+
+	assert.Equal(t, "home", rec.Selection)
+	assert.True(t, rec.Price.Equal(decimal.NewFromFloat(2.5)), rec.Price.String())
+	assert.True(t, rec.Matched.Equal(decimal.NewFromInt(100)))
+	assertMatches(t, map[string]float64{back.Id: 100, lay.Id: 100}, rec.Matches)
+}
+
+func TestReconcileLimitOnClose(t *testing.T) {
+	back := newTestSPBet(t, "alice", Back, 0, 100)
+	excluded := newTestSPBet(t, "alice", Back, 3.0, 50)
+	loc := newTestSPBet(t, "bob", Lay, 2.0, 300)
+	moc := newTestSPBet(t, "carol", Lay, 0, 50)
+
+	rec := Reconcile("home", NewOrderbook(), []*SPBet{back, excluded, loc, moc})
+
+	assert.True(t, rec.Price.Equal(decimal.NewFromFloat(2.0)), rec.Price.String())
+	assert.True(t, rec.Matched.Equal(decimal.NewFromInt(100)))
+	assertMatches(t, map[string]float64{back.Id: 100, loc.Id: 85.71, moc.Id: 14.28}, rec.Matches)
+}
+
+func TestReconcileExchangeLiquidity(t *testing.T) {
+	ob := NewOrderbook()
+	lay := newTestOrder(t, Lay, 3.0, 40.0)
+	back := newTestOrder(t, Back, 5.0, 10.0)
+	for _, o := range []*Order{lay, back} {
+		_, _, err := ob.AddOrder(o)
+		assert.Nil(t, err)
+	}
+	first := newTestSPBet(t, "alice", Back, 0, 60)
+	second := newTestSPBet(t, "bob", Back, 0, 40)
+
+	rec := Reconcile("home", ob, []*SPBet{first, second})
+
+	// backers get the price exchange liquidity was offered at
+	assert.True(t, rec.Price.Equal(decimal.NewFromFloat(3.0)), rec.Price.String())
+	assert.True(t, rec.Matched.Equal(decimal.NewFromInt(40)))
+	assertMatches(t, map[string]float64{first.Id: 24, second.Id: 16, lay.Id: 40}, rec.Matches)
+	for _, m := range rec.Matches {
+		assert.Equal(t, m.Id == lay.Id, m.Exchange)
+	}
+
+	// book is left untouched
+	assert.Equal(t, testAmount(40), lay.Stake)
+	assert.Equal(t, []*Order{lay}, ob.Orders(Lay))
+}
+
+func TestReconcileNothingToMatch(t *testing.T) {
+	rec := Reconcile("home", NewOrderbook(), nil)
+	assert.True(t, rec.Price.IsZero())
+	assert.Empty(t, rec.Matches)
+
+	rec = Reconcile("home", NewOrderbook(), []*SPBet{newTestSPBet(t, "alice", Back, 0, 10)})
+	assert.True(t, rec.Price.IsZero())
+	assert.True(t, rec.Matched.IsZero())
+	assert.Empty(t, rec.Matches)
+}
+
+func TestMarketPlaceSPBet(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+
+	err := m.PlaceSPBet("draw", newTestSPBet(t, "alice", Back, 0, 10))
+	assert.Equal(t, ErrSelectionNotFound, err)
+	assert.Nil(t, m.PlaceSPBet("home", newTestSPBet(t, "alice", Back, 0, 10)))
+
+	_, _, err = m.TurnInPlay()
+	assert.Nil(t, err)
+	err = m.PlaceSPBet("home", newTestSPBet(t, "alice", Back, 0, 10))
+	assert.Equal(t, ErrMarketStatus, err)
+}
+
+func TestMarketTurnInPlayStartingPrice(t *testing.T) {
+	m, _ := NewMarket("1", "home", "away")
+	back := newOwnedOrder(t, "alice", Back, 2.0, 10.0)
+	back.Persistence = MarketOnClose
+	lay := newOwnedOrder(t, "carol", Lay, 3.0, 10.0)
+	lay.Persistence = MarketOnClose
+	_, _, err := m.AddOrder("home", back)
+	assert.Nil(t, err)
+	_, _, err = m.AddOrder("away", lay)
+	assert.Nil(t, err)
+	assert.Nil(t, m.PlaceSPBet("home", newTestSPBet(t, "bob", Lay, 0, 30)))
+	assert.Nil(t, m.PlaceSPBet("away", newTestSPBet(t, "dave", Back, 0, 5)))
+
+	lapsed, sp, err := m.TurnInPlay()
+
+	assert.Nil(t, err)
+	assert.Len(t, sp, 2)
+	assert.True(t, sp[0].Price.Equal(decimal.NewFromInt(4)), sp[0].Price.String())
+	assert.True(t, sp[1].Price.Equal(decimal.NewFromInt(3)), sp[1].Price.String())
+
+	// back is matched in full, half of lay's liability is used and rest lapses
+	assert.Equal(t, Filled, back.Status)
+	assert.Zero(t, back.Stake)
+	assert.Equal(t, Cancelled, lay.Status)
+	assert.Equal(t, testAmount(5), lay.Stake)
+	assert.Equal(t, []*Order{lay}, lapsed)
+
+	assert.True(t, m.Exposure("alice").Equal(decimal.NewFromInt(10)))
+	assert.True(t, m.Exposure("bob").Equal(decimal.NewFromInt(30)))
+	assert.True(t, m.Exposure("carol").Equal(decimal.NewFromInt(10)))
+	assert.True(t, m.Exposure("dave").Equal(decimal.NewFromInt(5)))
+	assert.Empty(t, m.spBets)
+}
@@ -0,0 +1,51 @@
+package orderbook
+
+// SelfTradePrevention - what happens when an incoming order would match a
+// resting order of the same owner. Orders without owner are never checked
+type SelfTradePrevention int
+
+const (
+	CancelNewest SelfTradePrevention = iota // unmatched part of incoming order is cancelled
+	CancelOldest                            // resting order is cancelled, matching goes on
+	CancelBoth                              // both orders are cancelled
+	Decrement                               // both orders are reduced by the smaller stake without a trade
+)
+
+func (p SelfTradePrevention) String() string {
+	return [...]string{"CancelNewest", "CancelOldest", "CancelBoth", "Decrement"}[p]
+}
+
+// self - checks if resting order belongs to the owner of the order
+func (o *Order) self(maker *Order) bool {
+	return o.Owner != "" && o.Owner == maker.Owner
+}
+
+// preventSelfTrade - applies policy of the taker to its own resting order.
+// Returns false when the taker has to stop matching
+func (ob *Orderbook) preventSelfTrade(taker, maker *Order) bool {
+	switch taker.SelfTrade {
+	case CancelOldest:
+		ob.cancelSelf(maker.Id)
+		return true
+	case CancelBoth:
+		ob.cancelSelf(maker.Id)
+	case Decrement:
+		stake := minAmount(taker.Stake, maker.Stake)
+		taker.Stake -= stake
+		if stake == maker.Stake {
+			ob.cancelSelf(maker.Id)
+		} else {
+			ob.ReduceOrder(maker.Id, stake)
+		}
+		return taker.Stake > 0
+	}
+	return false
+}
+
+// cancelSelf - cancels resting order on behalf of self-trade prevention
+func (ob *Orderbook) cancelSelf(id string) {
+	o, err := ob.CancelOrder(id)
+	if err == nil && ob.cancelled != nil {
+		ob.cancelled(o)
+	}
+}
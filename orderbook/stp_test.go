@@ -0,0 +1,151 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSelfTradeBook - helper to create book with lays of bob and mm ahead of
+// incoming back of mm at the same price
+func newSelfTradeBook(t *testing.T) (ob *Orderbook, bob, mm *Order) {
+	t.Helper()
+
+	ob = NewOrderbook()
+	bob = newOwnedOrder(t, "bob", Lay, 2.0, 5.0)
+	mm = newOwnedOrder(t, "mm", Lay, 2.0, 10.0)
+	for _, o := range []*Order{bob, mm} {
+		if _, _, err := ob.AddOrder(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return
+}
+
+func TestSelfTradePreventionString(t *testing.T) {
+	assert.Equal(t, "CancelNewest", CancelNewest.String())
+	assert.Equal(t, "CancelOldest", CancelOldest.String())
+	assert.Equal(t, "CancelBoth", CancelBoth.String())
+	assert.Equal(t, "Decrement", Decrement.String())
+}
+
+func TestSelfTradeCancelNewest(t *testing.T) {
+	ob, bob, mm := newSelfTradeBook(t)
+	back := newOwnedOrder(t, "mm", Back, 2.0, 20.0)
+
+	trades, partial, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assertTrades(t, []Trade{{Seq: 1, MakerId: bob.Id, TakerId: back.Id, Side: Back, Price: testPrice(2.0), Stake: testAmount(5)}}, trades)
+	assert.Equal(t, back, partial)
+	assert.Equal(t, Cancelled, back.Status)
+	assert.Equal(t, testAmount(15), back.Stake)
+	assert.Equal(t, Resting, mm.Status)
+	assert.Equal(t, []*Order{mm}, ob.Orders(Lay))
+	assert.Empty(t, ob.Orders(Back))
+}
+
+func TestSelfTradeCancelOldest(t *testing.T) {
+	ob, _, mm := newSelfTradeBook(t)
+	back := newOwnedOrder(t, "mm", Back, 2.0, 20.0)
+	back.SelfTrade = CancelOldest
+
+	trades, partial, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, back, partial)
+	assert.Equal(t, Resting, back.Status)
+	assert.Equal(t, testAmount(15), back.Stake)
+	assert.Equal(t, Cancelled, mm.Status)
+	assert.Empty(t, ob.Orders(Lay))
+	assert.Equal(t, []*Order{back}, ob.Orders(Back))
+}
+
+func TestSelfTradeCancelBoth(t *testing.T) {
+	ob, _, mm := newSelfTradeBook(t)
+	back := newOwnedOrder(t, "mm", Back, 2.0, 20.0)
+	back.SelfTrade = CancelBoth
+
+	trades, _, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, Cancelled, back.Status)
+	assert.Equal(t, Cancelled, mm.Status)
+	assert.Empty(t, ob.Orders(Lay))
+	assert.Empty(t, ob.Orders(Back))
+}
+
+func TestSelfTradeDecrement(t *testing.T) {
+	ob, _, mm := newSelfTradeBook(t)
+	back := newOwnedOrder(t, "mm", Back, 2.0, 20.0)
+	back.SelfTrade = Decrement
+
+	trades, partial, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, back, partial)
+	assert.Equal(t, Resting, back.Status)
+	assert.Equal(t, testAmount(5), back.Stake)
+	assert.Equal(t, Cancelled, mm.Status)
+
+	// smaller taker is used up without a trade
+	lay := newOwnedOrder(t, "mm", Lay, 2.0, 3.0)
+	lay.SelfTrade = Decrement
+	trades, partial, err = ob.AddOrder(lay)
+
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Nil(t, partial)
+	assert.Equal(t, Cancelled, lay.Status)
+	assert.Zero(t, lay.Stake)
+	assert.Equal(t, testAmount(2), back.Stake)
+	assert.Equal(t, testPrice(2.0), ob.BestBack())
+}
+
+func TestSelfTradeFillOrKill(t *testing.T) {
+	ob, _, mm := newSelfTradeBook(t)
+	carol := newOwnedOrder(t, "carol", Lay, 2.0, 10.0)
+	_, _, err := ob.AddOrder(carol)
+	assert.Nil(t, err)
+
+	// volume behind own order is not available
+	back := newOwnedOrder(t, "mm", Back, 2.0, 15.0)
+	back.TimeInForce = FillOrKill
+	trades, _, err := ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Equal(t, Killed, back.Status)
+	assert.Equal(t, Resting, mm.Status)
+
+	back = newOwnedOrder(t, "mm", Back, 2.0, 15.0)
+	back.TimeInForce = FillOrKill
+	back.SelfTrade = CancelOldest
+	trades, _, err = ob.AddOrder(back)
+
+	assert.Nil(t, err)
+	assert.Len(t, trades, 2)
+	assert.Equal(t, Filled, back.Status)
+	assert.Equal(t, Cancelled, mm.Status)
+	assert.Empty(t, ob.Orders(Lay))
+}
+
+func TestMarketSelfTradeAccount(t *testing.T) {
+	m, _ := NewMarket("1", "home", "draw", "away")
+	lay := newOwnedOrder(t, "mm", Lay, 2.0, 10.0)
+	_, _, err := m.AddOrder("home", lay)
+	assert.Nil(t, err)
+
+	back := newOwnedOrder(t, "mm", Back, 2.0, 10.0)
+	back.SelfTrade = CancelOldest
+	trades, _, err := m.AddOrder("home", back)
+
+	assert.Nil(t, err)
+	assert.Empty(t, trades)
+	assert.Equal(t, map[string]*Order{back.Id: back}, m.account("mm").orders["home"])
+	assert.True(t, m.Exposure("mm").Equal(decimal.NewFromInt(10)))
+}
@@ -0,0 +1,119 @@
+package orderbook
+
+import (
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// Price - odds in hundredths, the smallest tick of any ladder, e.g. 2.5 is
+// 250. The engine compares, keys and stores prices as integers, decimals are
+// converted at the API boundary, so "2", "2.0" and "2.00" are the same price
+type Price int64
+
+// Amount - stake or volume in minor units of the currency, e.g. 10.5 is 1050
+type Amount int64
+
+const (
+	priceExp  = -2
+	amountExp = -2
+
+	priceOne Price = 100 // odds of 1.0, the lowest price that can never win
+)
+
+var maxUnits = decimal.NewFromInt(math.MaxInt64)
+
+// units - converts decimal to integer number of 10^exp units, fails when the
+// decimal is not a whole number of units or does not fit int64
+func units(d decimal.Decimal, exp int32) (int64, bool) {
+	shifted := d.Shift(-exp)
+	if !shifted.IsInteger() || shifted.Abs().GreaterThan(maxUnits) {
+		return 0, false
+	}
+	return shifted.IntPart(), true
+}
+
+// NewPrice - converts decimal odds to Price, ErrInvalidOrderPrice when the
+// price is finer than a hundredth
+func NewPrice(d decimal.Decimal) (Price, error) {
+	p, ok := units(d, priceExp)
+	if !ok {
+		return 0, ErrInvalidOrderPrice
+	}
+	return Price(p), nil
+}
+
+// NewAmount - converts decimal stake to Amount, ErrInvalidStake when the
+// stake is finer than a minor unit
+func NewAmount(d decimal.Decimal) (Amount, error) {
+	a, ok := units(d, amountExp)
+	if !ok {
+		return 0, ErrInvalidStake
+	}
+	return Amount(a), nil
+}
+
+// amountFloor - converts result of decimal arithmetic to Amount rounding it
+// down to a minor unit
+func amountFloor(d decimal.Decimal) Amount {
+	return Amount(d.Shift(-amountExp).Floor().IntPart())
+}
+
+func (p Price) Decimal() decimal.Decimal {
+	return decimal.New(int64(p), priceExp)
+}
+
+func (p Price) String() string {
+	return p.Decimal().String()
+}
+
+// MarshalJSON - prices are encoded as decimals, e.g. "2.5"
+func (p Price) MarshalJSON() ([]byte, error) {
+	return p.Decimal().MarshalJSON()
+}
+
+func (p *Price) UnmarshalJSON(b []byte) error {
+	var d decimal.Decimal
+	if err := d.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	v, err := NewPrice(d)
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+func (a Amount) Decimal() decimal.Decimal {
+	return decimal.New(int64(a), amountExp)
+}
+
+func (a Amount) String() string {
+	return a.Decimal().String()
+}
+
+// MarshalJSON - amounts are encoded as decimals, e.g. "10.5"
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return a.Decimal().MarshalJSON()
+}
+
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	var d decimal.Decimal
+	if err := d.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	v, err := NewAmount(d)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+func minAmount(a, b Amount) Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
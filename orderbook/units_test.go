@@ -0,0 +1,167 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPrice, testAmount - engine values of literals used in tests
+func testPrice(f float64) Price {
+	return Price(math.Round(f * 100))
+}
+
+func testAmount(f float64) Amount {
+	return Amount(math.Round(f * 100))
+}
+
+func TestNewPrice(t *testing.T) {
+	for s, p := range map[string]Price{
+		"1.01":   101,
+		"2":      200,
+		"2.0":    200,
+		"2.50":   250,
+		"2.5000": 250,
+		"1000":   100000,
+		"1e2":    10000,
+	} {
+		price, err := NewPrice(decimal.RequireFromString(s))
+		assert.Nil(t, err, s)
+		assert.Equal(t, p, price, s)
+		assert.True(t, price.Decimal().Equal(decimal.RequireFromString(s)), s)
+	}
+
+	for _, s := range []string{"1.005", "2.0001", "1e30"} {
+		_, err := NewPrice(decimal.RequireFromString(s))
+		assert.Equal(t, ErrInvalidOrderPrice, err, s)
+	}
+}
+
+func TestNewAmount(t *testing.T) {
+	for s, a := range map[string]Amount{
+		"0":     0,
+		"10":    1000,
+		"10.5":  1050,
+		"0.01":  1,
+		"-3.20": -320,
+	} {
+		amount, err := NewAmount(decimal.RequireFromString(s))
+		assert.Nil(t, err, s)
+		assert.Equal(t, a, amount, s)
+		assert.True(t, amount.Decimal().Equal(decimal.RequireFromString(s)), s)
+	}
+
+	_, err := NewAmount(decimal.RequireFromString("0.001"))
+	assert.Equal(t, ErrInvalidStake, err)
+
+	assert.Equal(t, Amount(1234), amountFloor(decimal.RequireFromString("12.349")))
+}
+
+func TestUnitsJSON(t *testing.T) {
+	b, err := json.Marshal(Level{Price: 250, Volume: 1050, Orders: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"price":"2.5","volume":"10.5","orders":2}`, string(b))
+
+	var l Level
+	assert.Nil(t, json.Unmarshal([]byte(`{"price":2.50,"volume":"10.5"}`), &l))
+	assert.Equal(t, Level{Price: 250, Volume: 1050}, l)
+
+	assert.Equal(t, ErrInvalidOrderPrice, json.Unmarshal([]byte(`{"price":"2.505"}`), &l))
+	assert.Equal(t, ErrInvalidStake, json.Unmarshal([]byte(`{"volume":"0.001"}`), &l))
+}
+
+func TestNewOrderConvertsAtBoundary(t *testing.T) {
+	_, err := NewOrder(Back, decimal.NewFromFloat(2.5), decimal.RequireFromString("10.001"))
+	assert.Equal(t, ErrInvalidStake, err)
+	_, err = NewOrder(Back, decimal.RequireFromString("0.995"), decimal.NewFromInt(10))
+	assert.Equal(t, ErrInvalidOrderPrice, err)
+
+	o, err := NewOrder(Lay, decimal.RequireFromString("2.50"), decimal.RequireFromString("10.5"))
+	assert.Nil(t, err)
+	assert.Equal(t, Price(250), o.Price)
+	assert.Equal(t, Amount(1050), o.Stake)
+	assert.True(t, o.Liability().Equal(decimal.RequireFromString("15.75")))
+}
+
+// Benchmarks below compare operations of the matching engine on decimals it
+// used before with the integers it uses now
+
+func BenchmarkLevelLookupDecimal(b *testing.B) {
+	prices := benchPrices(b, 1.5, benchLevels)
+	limits := make(map[string]*Limit, len(prices))
+	for _, p := range prices {
+		limits[p.String()] = &Limit{}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = limits[prices[i%len(prices)].String()]
+	}
+}
+
+func BenchmarkLevelLookupInteger(b *testing.B) {
+	prices := benchPrices(b, 1.5, benchLevels)
+	keys := make([]Price, len(prices))
+	limits := make(map[Price]*Limit, len(prices))
+	for i, p := range prices {
+		keys[i], _ = NewPrice(p)
+		limits[keys[i]] = &Limit{}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = limits[keys[i%len(keys)]]
+	}
+}
+
+// sinks - keep results of benchmark loops alive, so that the compiler does
+// not remove the loops
+var (
+	decimalSink decimal.Decimal
+	amountSink  Amount
+)
+
+func BenchmarkFillDecimal(b *testing.B) {
+	stake, volume := decimal.RequireFromString("1000000"), decimal.RequireFromString("10.5")
+	price, limit := decimal.RequireFromString("2.5"), decimal.RequireFromString("2.48")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if price.GreaterThanOrEqual(limit) {
+			fill := decimal.Min(stake, volume)
+			stake = stake.Sub(fill)
+		}
+	}
+	decimalSink = stake
+}
+
+func BenchmarkFillInteger(b *testing.B) {
+	stake, volume := Amount(100000000), Amount(1050)
+	price, limit := Price(250), Price(248)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if price >= limit {
+			fill := minAmount(stake, volume)
+			stake -= fill
+		}
+	}
+	amountSink = stake
+}
+
+func BenchmarkConvert(b *testing.B) {
+	price, stake := decimal.RequireFromString("2.5"), decimal.RequireFromString("10.5")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convert(price, stake)
+	}
+}